@@ -0,0 +1,40 @@
+package datadog
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// commonColumns adds the columns identifying the org of the connection, so rows
+// of aggregator connections spanning several orgs can be told apart.
+func commonColumns(c []*plugin.Column) []*plugin.Column {
+	return append(c, []*plugin.Column{
+		{Name: "org_name", Type: proto.ColumnType_STRING, Hydrate: getOrgInfo, Transform: transform.FromField("OrgName"), Description: "The name of the Datadog organization the resource belongs to."},
+		{Name: "org_public_id", Type: proto.ColumnType_STRING, Hydrate: getOrgInfo, Transform: transform.FromField("OrgPublicID"), Description: "The public ID of the Datadog organization the resource belongs to."},
+	}...)
+}
+
+func getOrgInfo(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	info, err := getConnectionInfo(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog.getOrgInfo", "connection_error", err)
+		return nil, err
+	}
+	return info, nil
+}
+
+// getOrgPublicID returns the org of the connection, which is used to filter the
+// connections of an aggregator on the org_public_id column.
+func getOrgPublicID(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	info, err := getConnectionInfo(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		return nil, err
+	}
+	if info.OrgPublicID == nil {
+		return "", nil
+	}
+	return *info.OrgPublicID, nil
+}
@@ -1,17 +1,82 @@
 package datadog
 
 import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 )
 
 type datadogConfig struct {
 	APIKey *string `hcl:"api_key"`
 	AppKey *string `hcl:"app_key"`
+	// Files containing the keys, as an alternative to setting them inline
+	APIKeyFile *string `hcl:"api_key_file"`
+	AppKeyFile *string `hcl:"app_key_file"`
+	// A command printing the keys as JSON, e.g. {"api_key": "...", "app_key": "..."}
+	CredentialCommand *string `hcl:"credential_command"`
+	// The authentication mode, "api_key" (default) or "bearer" for OAuth access tokens
+	AuthType    *string `hcl:"auth_type"`
+	AccessToken *string `hcl:"access_token"`
+	// The Datadog site to connect to, e.g. "us1", "us3", "us5", "eu1", "ap1" or "gov".
+	// The full site domain, e.g. "datadoghq.eu", is also accepted.
+	Site *string `hcl:"site"`
 	// By default it is https://api.datadoghq.com/
 	// If working with "EU" version of Datadog, use https://api.datadoghq.eu/
+	// Takes precedence over site, use it for proxies and private endpoints.
 	ApiURL *string `hcl:"api_url"`
+
+	// Retry and backoff policy for throttled (429) and failed (5xx) requests
+	MaxErrorRetryAttempts *int `hcl:"max_error_retry_attempts"`
+	MinErrorRetryDelay    *int `hcl:"min_error_retry_delay"`
+	MaxErrorRetryDelay    *int `hcl:"max_error_retry_delay"`
+	ErrorRetryTimeout     *int `hcl:"error_retry_timeout"`
+
+	// The number of pages a table fetches at once, capped per table by tablePageConcurrency
+	MaxConcurrency *int `hcl:"max_concurrency"`
+
+	// The number of slices the time range of a log event search is split into, and the
+	// number of log searches the connection sends at once across its queries
+	LogSlices         *int `hcl:"log_slices"`
+	LogMaxConcurrency *int `hcl:"log_max_concurrency"`
+
+	// Requests per second allowed per API family, e.g. {logs_search = 1}, see apiFamilies
+	RateLimits map[string]float64 `hcl:"rate_limits,optional"`
+
+	// HTTP status codes of errors that make a table return no rows instead of failing.
+	// Replaces the per-table defaults when set.
+	IgnoreErrorCodes []int `hcl:"ignore_error_codes,optional"`
+
+	// Transport settings for egress proxies and TLS inspection
+	ProxyURL              *string `hcl:"proxy_url"`
+	CACertificateFile     *string `hcl:"ca_certificate_file"`
+	InsecureSkipVerify    *bool   `hcl:"insecure_skip_verify"`
+	ClientCertificateFile *string `hcl:"client_certificate_file"`
+	ClientKeyFile         *string `hcl:"client_key_file"`
 }
 
+// datadogSites maps the short name of each Datadog site to its domain.
+// https://docs.datadoghq.com/getting_started/site/
+var datadogSites = map[string]string{
+	"us1": "datadoghq.com",
+	"us3": "us3.datadoghq.com",
+	"us5": "us5.datadoghq.com",
+	"eu1": "datadoghq.eu",
+	"ap1": "ap1.datadoghq.com",
+	"gov": "ddog-gov.com",
+}
+
+// Supported authentication modes.
+const (
+	authTypeAPIKey = "api_key"
+	authTypeBearer = "bearer"
+)
+
+// defaultSite is used when neither a site nor an API URL is configured.
+const defaultSite = "us1"
+
 func ConfigInstance() interface{} {
 	return &datadogConfig{}
 }
@@ -24,3 +89,56 @@ func GetConfig(connection *plugin.Connection) datadogConfig {
 	config, _ := connection.Config.(datadogConfig)
 	return config
 }
+
+// validateConfig checks the arguments of the connection config that need no request
+// to Datadog, so e.g. a mistyped site fails with a clear message before any request
+// is sent.
+func validateConfig(connection *plugin.Connection) error {
+	config := GetConfig(connection)
+
+	if config.AuthType != nil && *config.AuthType != "" {
+		switch authType := strings.ToLower(*config.AuthType); authType {
+		case authTypeAPIKey, authTypeBearer:
+		default:
+			return fmt.Errorf("invalid auth_type %q, must be one of: %s, %s", authType, authTypeAPIKey, authTypeBearer)
+		}
+	}
+
+	if _, err := getAPIURL(connection); err != nil {
+		return err
+	}
+
+	return validateRateLimits(config.RateLimits)
+}
+
+// siteDomain returns the domain of the given site, which may either be a short
+// site name like "eu1" or a site domain like "datadoghq.eu".
+func siteDomain(site string) (string, error) {
+	site = strings.ToLower(strings.TrimSpace(site))
+	if domain, ok := datadogSites[site]; ok {
+		return domain, nil
+	}
+	for _, domain := range datadogSites {
+		if site == domain {
+			return domain, nil
+		}
+	}
+
+	names := make([]string, 0, len(datadogSites))
+	for name := range datadogSites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("invalid site %q, must be one of: %s", site, strings.Join(names, ", "))
+}
+
+// siteName returns the short name of the site the API URL belongs to, or the host
+// of the API URL if it does not belong to a known site, e.g. for private endpoints.
+func siteName(apiURL *url.URL) string {
+	for name, domain := range datadogSites {
+		if apiURL.Host == "api."+domain {
+			return name
+		}
+	}
+	return apiURL.Host
+}
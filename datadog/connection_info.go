@@ -0,0 +1,244 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	datadogV1 "github.com/DataDog/datadog-api-client-go/api/v1/datadog"
+	connectionmanager "github.com/turbot/steampipe-plugin-sdk/v5/connection"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"golang.org/x/sync/singleflight"
+)
+
+// connectionInfoCalls deduplicates concurrent validations by connection name, so the
+// keys of a connection are validated once while other connections validate in parallel.
+var connectionInfoCalls singleflight.Group
+
+// connectionInfoErrorTTL is how long a failed validation is cached. Queries fail with
+// the cached error until then, after which the keys are validated again, e.g. once
+// the keys were fixed in Datadog or a network outage is over.
+const connectionInfoErrorTTL = time.Minute
+
+// connectionInfo describes whom a connection is authenticated as, as reported by Datadog.
+type connectionInfo struct {
+	Site           string
+	APIURL         string
+	OrgName        *string
+	OrgPublicID    *string
+	KeyOwnerID     *string
+	KeyOwnerHandle *string
+	KeyOwnerEmail  *string
+	AppKeyName     *string
+	AppKeyScoped   *bool
+	AppKeyScopes   []string
+	APIVersions    []string
+}
+
+// applicationKeysResponse is the subset of the current user application keys
+// response used to identify the configured application key.
+type applicationKeysResponse struct {
+	Data []struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Name   string   `json:"name"`
+			Last4  string   `json:"last4"`
+			Scopes []string `json:"scopes"`
+		} `json:"attributes"`
+		Relationships struct {
+			OwnedBy struct {
+				Data struct {
+					ID string `json:"id"`
+				} `json:"data"`
+			} `json:"owned_by"`
+		} `json:"relationships"`
+	} `json:"data"`
+}
+
+// connectionInfoResult is the outcome of validating a connection, as cached.
+type connectionInfoResult struct {
+	info *connectionInfo
+	err  error
+}
+
+// getConnectionInfo validates the keys of the connection and looks up the org and
+// owner of the keys. It runs on the first connect of the connection. The result is
+// cached, failures included, so validation happens once per connection rather than
+// once per query.
+func getConnectionInfo(ctx context.Context, connection *plugin.Connection, cache *connectionmanager.ConnectionCache) (*connectionInfo, error) {
+	if cachedData, ok := cache.Get(ctx, cacheKeyConnectionInfo); ok {
+		result := cachedData.(*connectionInfoResult)
+		return result.info, result.err
+	}
+
+	// The validation outlives the query which started it, as other queries may wait for it
+	calls := connectionInfoCalls.DoChan(connection.Name, func() (interface{}, error) {
+		validateCtx := context.WithoutCancel(ctx)
+		if cachedData, ok := cache.Get(validateCtx, cacheKeyConnectionInfo); ok {
+			return cachedData, nil
+		}
+		info, err := loadConnectionInfo(validateCtx, connection, cache)
+		result := &connectionInfoResult{info: info, err: err}
+
+		// Save to cache
+		if err != nil {
+			_ = cache.SetWithTTL(validateCtx, cacheKeyConnectionInfo, result, connectionInfoErrorTTL)
+		} else {
+			_ = cache.Set(validateCtx, cacheKeyConnectionInfo, result)
+		}
+		return result, nil
+	})
+
+	select {
+	case call := <-calls:
+		result := call.Val.(*connectionInfoResult)
+		return result.info, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loadConnectionInfo validates the keys of the connection with Datadog.
+func loadConnectionInfo(ctx context.Context, connection *plugin.Connection, cache *connectionmanager.ConnectionCache) (*connectionInfo, error) {
+	settings, err := getConnectionSettings(ctx, connection, cache)
+	if err != nil {
+		return nil, err
+	}
+	clientV1, err := getClientV1(ctx, connection, cache)
+	if err != nil {
+		return nil, err
+	}
+	clientV2, err := getClientV2(ctx, connection, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &connectionInfo{
+		Site:        settings.site,
+		APIURL:      settings.apiURL.String(),
+		APIVersions: []string{},
+	}
+
+	ctxV1 := contextV1(ctx, settings)
+	ctxV2 := contextV2(ctx, settings)
+
+	if settings.authType == authTypeBearer {
+		// Access tokens cannot be checked with the validate endpoint, which requires an API key
+		orgs, httpResp, err := clientV1.OrganizationsApi.ListOrgs(ctxV1)
+		if err != nil {
+			if httpResp != nil && httpResp.StatusCode == http.StatusUnauthorized {
+				return nil, fmt.Errorf("the access_token was rejected by Datadog site %s (%s), check the access_token has not expired and the site or api_url arguments", settings.site, settings.apiURL.Host)
+			}
+			plugin.Logger(ctx).Warn("datadog.getConnectionInfo", "org_error", err)
+		} else {
+			setOrg(info, orgs)
+		}
+		info.APIVersions = append(info.APIVersions, "v1", "v2")
+
+		return info, nil
+	}
+
+	// https://docs.datadoghq.com/api/latest/authentication/#validate-api-key
+	_, httpResp, err := clientV1.AuthenticationApi.Validate(ctxV1)
+	if err != nil {
+		if httpResp != nil && (httpResp.StatusCode == http.StatusUnauthorized || httpResp.StatusCode == http.StatusForbidden) {
+			return nil, fmt.Errorf("the api_key was rejected by Datadog site %s (%s), check the api_key and the site or api_url arguments", settings.site, settings.apiURL.Host)
+		}
+		return nil, fmt.Errorf("failed to validate the api_key with Datadog site %s (%s): %v", settings.site, settings.apiURL.Host, err)
+	}
+	info.APIVersions = append(info.APIVersions, "v1")
+
+	// https://docs.datadoghq.com/api/latest/organizations/#list-your-managed-organizations
+	orgs, httpResp, err := clientV1.OrganizationsApi.ListOrgs(ctxV1)
+	if err != nil {
+		if isAppKeyRejected(httpResp, err) {
+			return nil, fmt.Errorf("the app_key was rejected by Datadog site %s (%s), check the app_key belongs to the same organization as the api_key", settings.site, settings.apiURL.Host)
+		}
+		plugin.Logger(ctx).Warn("datadog.getConnectionInfo", "org_error", err)
+	} else {
+		setOrg(info, orgs)
+	}
+
+	rawClient, err := getRawClient(ctx, connection, cache, settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := lookupApplicationKey(ctx, rawClient, info); err != nil {
+		plugin.Logger(ctx).Warn("datadog.getConnectionInfo", "application_key_error", err)
+	}
+
+	if info.KeyOwnerID != nil {
+		// https://docs.datadoghq.com/api/latest/users/#get-user-details
+		user, _, err := clientV2.UsersApi.GetUser(ctxV2, *info.KeyOwnerID)
+		if err != nil {
+			plugin.Logger(ctx).Warn("datadog.getConnectionInfo", "key_owner_error", err)
+		} else {
+			data := user.GetData()
+			attributes := data.GetAttributes()
+			info.KeyOwnerHandle = attributes.Handle
+			info.KeyOwnerEmail = attributes.Email
+		}
+	}
+
+	return info, nil
+}
+
+// setOrg records the org the credentials belong to.
+func setOrg(info *connectionInfo, orgs datadogV1.OrganizationListResponse) {
+	if len(orgs.GetOrgs()) > 0 {
+		org := orgs.GetOrgs()[0]
+		info.OrgName = org.Name
+		info.OrgPublicID = org.PublicId
+	}
+}
+
+// lookupApplicationKey finds the configured application key among the keys of the
+// current user, to report its owner and scopes. The API only returns the last 4
+// characters of the keys, so nothing is reported when several keys end with them.
+func lookupApplicationKey(ctx context.Context, client *rawClient, info *connectionInfo) error {
+	last4 := client.settings.appKey
+	if len(last4) > 4 {
+		last4 = last4[len(last4)-4:]
+	}
+
+	pageSize := 100
+	firstPage := true
+	matches := 0
+	err := listPageNumbers(ctx, client, "/api/v2/current_user/application_keys", nil, pageSize, func(keys *applicationKeysResponse) (bool, error) {
+		if firstPage {
+			info.APIVersions = append(info.APIVersions, "v2")
+			firstPage = false
+		}
+		for _, key := range keys.Data {
+			if key.Attributes.Last4 != last4 {
+				continue
+			}
+			matches++
+			name := key.Attributes.Name
+			scoped := len(key.Attributes.Scopes) > 0
+			info.AppKeyName = &name
+			info.AppKeyScoped = &scoped
+			info.AppKeyScopes = key.Attributes.Scopes
+			if owner := key.Relationships.OwnedBy.Data.ID; owner != "" {
+				info.KeyOwnerID = &owner
+			}
+		}
+		return len(keys.Data) == pageSize, nil
+	})
+	if err != nil {
+		return err
+	}
+	if matches > 1 {
+		info.AppKeyName, info.AppKeyScoped, info.AppKeyScopes, info.KeyOwnerID = nil, nil, nil, nil
+		return fmt.Errorf("%d application keys end with the last 4 characters of the app_key, the key is ambiguous", matches)
+	}
+	return nil
+}
+
+// isAppKeyRejected reports whether the application key itself was rejected by the
+// org lookup. Datadog answers invalid application keys with 401 or 403.
+func isAppKeyRejected(httpResp *http.Response, err error) bool {
+	apiErr := toAPIError(httpResp, err)
+	return apiErr != nil && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
@@ -0,0 +1,141 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// credentialCommandTimeout limits how long the credential command may run.
+var credentialCommandTimeout = 30 * time.Second
+
+// credentialCommandOutput is the JSON document the credential command must print to stdout.
+type credentialCommandOutput struct {
+	APIKey string `json:"api_key"`
+	AppKey string `json:"app_key"`
+}
+
+// resolveCredentials resolves the API and application keys of the connection in below order:
+//  1. The "api_key"/"app_key" specified in the config
+//  2. The "api_key_file"/"app_key_file" specified in the config
+//  3. The output of the "credential_command" specified in the config
+//  4. The `DD_CLIENT_API_KEY`/`DD_CLIENT_APP_KEY` environment variables
+//  5. The `DD_API_KEY`/`DD_APP_KEY` environment variables
+func resolveCredentials(ctx context.Context, connection *plugin.Connection) (string, string, error) {
+	config := GetConfig(connection)
+
+	apiKey, apiKeySource, err := resolveKey(config.APIKey, "api_key", config.APIKeyFile, "api_key_file")
+	if err != nil {
+		return "", "", err
+	}
+	appKey, appKeySource, err := resolveKey(config.AppKey, "app_key", config.AppKeyFile, "app_key_file")
+	if err != nil {
+		return "", "", err
+	}
+
+	if (apiKey == "" || appKey == "") && config.CredentialCommand != nil && *config.CredentialCommand != "" {
+		output, err := runCredentialCommand(ctx, *config.CredentialCommand)
+		if err != nil {
+			return "", "", err
+		}
+		if apiKey == "" && output.APIKey != "" {
+			apiKey, apiKeySource = output.APIKey, "credential_command"
+		}
+		if appKey == "" && output.AppKey != "" {
+			appKey, appKeySource = output.AppKey, "credential_command"
+		}
+	}
+
+	if apiKey == "" {
+		apiKey, apiKeySource = firstEnv("DD_CLIENT_API_KEY", "DD_API_KEY")
+	}
+	if appKey == "" {
+		appKey, appKeySource = firstEnv("DD_CLIENT_APP_KEY", "DD_APP_KEY")
+	}
+
+	plugin.Logger(ctx).Debug("datadog.resolveCredentials", "connection", connection.Name, "api_key_source", apiKeySource, "app_key_source", appKeySource)
+
+	return apiKey, appKey, nil
+}
+
+// resolveKey returns the key from the config argument or the file argument, along with its source.
+func resolveKey(value *string, valueArg string, file *string, fileArg string) (string, string, error) {
+	if value != nil && *value != "" {
+		return *value, valueArg, nil
+	}
+	if file != nil && *file != "" {
+		path, err := expandHome(*file)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read %s: %v", fileArg, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read %s: %v", fileArg, err)
+		}
+		key := strings.TrimSpace(string(content))
+		if key == "" {
+			return "", "", fmt.Errorf("%s is empty: %s", fileArg, *file)
+		}
+		return key, fileArg, nil
+	}
+	return "", "", nil
+}
+
+// expandHome replaces a leading "~/" of the path with the home directory of the user,
+// as in the documented "~/.datadog/api_key".
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// runCredentialCommand runs the command through the shell and parses the keys it prints as JSON.
+func runCredentialCommand(ctx context.Context, command string) (*credentialCommandOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Never include stdout in the error as it may contain secrets
+		return nil, fmt.Errorf("credential_command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	output := &credentialCommandOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return nil, fmt.Errorf("credential_command must print a JSON object with api_key and app_key: %v", err)
+	}
+	return output, nil
+}
+
+// firstEnv returns the value and name of the first environment variable that is set.
+func firstEnv(names ...string) (string, string) {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value, name
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,33 @@
+package datadog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveKeyFromFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".datadog"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".datadog", "api_key"), []byte("file-api-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"~/.datadog/api_key", filepath.Join(home, ".datadog", "api_key")} {
+		key, source, err := resolveKey(nil, "api_key", &path, "api_key_file")
+		if err != nil {
+			t.Fatalf("resolveKey(%q) failed: %v", path, err)
+		}
+		if key != "file-api-key" || source != "api_key_file" {
+			t.Errorf("resolveKey(%q) = %q from %s, want file-api-key from api_key_file", path, key, source)
+		}
+	}
+
+	missing := "~/.datadog/app_key"
+	if _, _, err := resolveKey(nil, "app_key", &missing, "app_key_file"); err == nil {
+		t.Error("resolveKey of a missing file succeeded, want an error")
+	}
+}
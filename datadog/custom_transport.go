@@ -7,27 +7,44 @@ import (
 	"context"
 	"io"
 	"math"
+	"math/rand"
 	"net/http"
 	"strconv"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 var (
-	defaultHTTPRetryDuration = 5 * time.Second
-	defaultHTTPRetryTimeout  = 60 * time.Second
-	rateLimitResetHeader     = "X-Ratelimit-Reset"
+	defaultHTTPRetryTimeout    = 60 * time.Second
+	defaultHTTPRetryMaxRetries = 10
+	defaultHTTPRetryMinBackoff = 1 * time.Second
+	defaultHTTPRetryMaxBackoff = 30 * time.Second
+	rateLimitResetHeader       = "X-Ratelimit-Reset"
 )
 
 // CustomTransport holds DefaultTransport configuration and is used to for custom http error handling
 type CustomTransport struct {
-	defaultTransport  http.RoundTripper
-	httpRetryDuration time.Duration
-	httpRetryTimeout  time.Duration
+	defaultTransport    http.RoundTripper
+	httpRetryTimeout    time.Duration
+	httpRetryMaxRetries int
+	httpRetryMinBackoff time.Duration
+	httpRetryMaxBackoff time.Duration
+	rateLimits          *rateLimitTracker
+	familyLimiters      map[string]*rate.Limiter
 }
 
 // CustomTransportOptions Set options for CustomTransport
 type CustomTransportOptions struct {
+	// Total time allowed for a request including all of its retries
 	Timeout *time.Duration
+	// Maximum number of retries of a request
+	MaxRetries *int
+	// Minimum and maximum wait between retries when the API does not tell us how long to wait
+	MinBackoff *time.Duration
+	MaxBackoff *time.Duration
+	// Limiters of the requests of API families, see newFamilyLimiters
+	FamilyLimiters map[string]*rate.Limiter
 }
 
 // RoundTrip method used to retry http errors
@@ -39,37 +56,76 @@ func (t *CustomTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		defer ccancel()
 	}
 
+	// Count the calls to the endpoint for the diagnostics of the query
+	var calls endpointStats
+	var backoff, rateLimitWait, duration time.Duration
+	defer func() {
+		calls.BackoffMs = backoff.Milliseconds()
+		calls.RateLimitWaitMs = rateLimitWait.Milliseconds()
+		calls.DurationMs = duration.Milliseconds()
+		queryStatsFromContext(ctx).record(endpointName(req.Method, req.URL.Path), calls)
+	}()
+
+	family := rateLimitFamily(req)
 	retryCount := 0
 	for {
+		waitStart := time.Now()
+		// Hold the request back while the rate limit budget of its endpoint family is used up
+		if err := t.rateLimits.wait(ctx, family); err != nil {
+			return nil, err
+		}
+		// Hold the request back while the rate_limits of its API family are used up
+		if limiter, ok := t.familyLimiters[apiFamilyOfPath(req.URL.Path)]; ok {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		rateLimitWait += time.Since(waitStart)
+
 		newRequest := t.copyRequest(req)
+		calls.Requests++
+		if newRequest.ContentLength > 0 {
+			calls.BytesSent += newRequest.ContentLength
+		}
+		requestStart := time.Now()
 		resp, respErr := t.defaultTransport.RoundTrip(newRequest)
 		// Close the body so connection can be re-used
 		if resp != nil {
 			localVarBody, _ := io.ReadAll(resp.Body)
 			resp.Body.Close()
 			resp.Body = io.NopCloser(bytes.NewBuffer(localVarBody))
+			calls.BytesReceived += int64(len(localVarBody))
 		}
+		duration += time.Since(requestStart)
 		if respErr != nil {
 			return resp, respErr
 		}
+		t.rateLimits.update(family, resp)
 
 		// Check if request should be retried and get retry time
 		retryDuration, retry := t.retryRequest(resp)
-		if !retry {
+		if !retry || retryCount >= t.httpRetryMaxRetries {
 			return resp, respErr
 		}
 
 		// Calculate retryDuration if nil
 		if retryDuration == nil {
-			newRetryDurationVal := time.Duration(retryCount) * t.httpRetryDuration
+			newRetryDurationVal := t.DefaultBackoff(t.httpRetryMinBackoff, t.httpRetryMaxBackoff, retryCount, resp)
 			retryDuration = &newRetryDurationVal
 		}
 
+		// Give up straight away if the wait would not finish before the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(*retryDuration).After(deadline) {
+			return resp, respErr
+		}
+
 		select {
 		case <-ctx.Done():
 			return resp, respErr
 		case <-time.After(*retryDuration):
 			retryCount++
+			calls.Retries++
+			backoff += *retryDuration
 			continue
 		}
 	}
@@ -89,13 +145,15 @@ func (t *CustomTransport) copyRequest(r *http.Request) *http.Request {
 }
 
 func (t *CustomTransport) retryRequest(response *http.Response) (*time.Duration, bool) {
-	if v := response.Header.Get(rateLimitResetHeader); v != "" && response.StatusCode == 429 {
-		vInt, err := strconv.ParseInt(v, 10, 64)
-		if err != nil {
-			return nil, true
+	if response.StatusCode == http.StatusTooManyRequests {
+		if v := response.Header.Get(rateLimitResetHeader); v != "" {
+			vInt, err := strconv.ParseInt(v, 10, 64)
+			if err == nil {
+				retryDuration := time.Duration(vInt) * time.Second
+				return &retryDuration, true
+			}
 		}
-		retryDuration := time.Duration(vInt) * time.Second
-		return &retryDuration, true
+		return nil, true
 	}
 
 	if response.StatusCode >= 500 {
@@ -107,28 +165,47 @@ func (t *CustomTransport) retryRequest(response *http.Response) (*time.Duration,
 
 // NewCustomTransport returns new CustomTransport struct
 func NewCustomTransport(t http.RoundTripper, opt CustomTransportOptions) *CustomTransport {
-	// Use default transport if one provided is nil
+	// Use a copy of the default transport if one provided is nil
 	if t == nil {
-		t = http.DefaultTransport
+		t = http.DefaultTransport.(*http.Transport).Clone()
 	}
 
 	ct := CustomTransport{
-		defaultTransport:  t,
-		httpRetryDuration: defaultHTTPRetryDuration,
+		defaultTransport:    t,
+		httpRetryTimeout:    defaultHTTPRetryTimeout,
+		httpRetryMaxRetries: defaultHTTPRetryMaxRetries,
+		httpRetryMinBackoff: defaultHTTPRetryMinBackoff,
+		httpRetryMaxBackoff: defaultHTTPRetryMaxBackoff,
+		rateLimits:          newRateLimitTracker(),
+		familyLimiters:      opt.FamilyLimiters,
 	}
 
 	if opt.Timeout != nil {
 		ct.httpRetryTimeout = *opt.Timeout
-	} else {
-		ct.httpRetryTimeout = defaultHTTPRetryTimeout
+	}
+	if opt.MaxRetries != nil {
+		ct.httpRetryMaxRetries = *opt.MaxRetries
+	}
+	if opt.MinBackoff != nil {
+		ct.httpRetryMinBackoff = *opt.MinBackoff
+	}
+	if opt.MaxBackoff != nil {
+		ct.httpRetryMaxBackoff = *opt.MaxBackoff
+	}
+	if ct.httpRetryMaxBackoff < ct.httpRetryMinBackoff {
+		ct.httpRetryMaxBackoff = ct.httpRetryMinBackoff
 	}
 
 	return &ct
 }
 
+// DefaultBackoff provides an exponential backoff with jitter based on the attempt number
+// and limited by the provided minimum and maximum durations.
+//
 // It also tries to parse Retry-After response header when a http.StatusTooManyRequests
-// (HTTP Code 429) is found in the resp parameter. Hence it will return the number of
-// seconds the server states it may be ready to process more requests from this client.
+// (HTTP Code 429) or http.StatusServiceUnavailable (HTTP Code 503) is found in the resp
+// parameter. Hence it will return the number of seconds the server states it may be
+// ready to process more requests from this client.
 func (t *CustomTransport) DefaultBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
 	if resp != nil {
 		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
@@ -145,5 +222,13 @@ func (t *CustomTransport) DefaultBackoff(min, max time.Duration, attemptNum int,
 	if float64(sleep) != mult || sleep > max {
 		sleep = max
 	}
+
+	// Spread out retries of concurrent requests by waiting between half and the full backoff
+	if half := int64(sleep / 2); half > 0 {
+		sleep = time.Duration(half + rand.Int63n(half+1))
+	}
+	if sleep < min {
+		sleep = min
+	}
 	return sleep
 }
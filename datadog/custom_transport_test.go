@@ -0,0 +1,205 @@
+package datadog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+func newTestTransport(maxRetries int) *CustomTransport {
+	minBackoff, maxBackoff := time.Millisecond, 10*time.Millisecond
+	return NewCustomTransport(nil, CustomTransportOptions{
+		MaxRetries: &maxRetries,
+		MinBackoff: &minBackoff,
+		MaxBackoff: &maxBackoff,
+	})
+}
+
+// serveStatuses returns the status codes in order, and 200 once all were served.
+func serveStatuses(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(atomic.AddInt32(&calls, 1)) - 1
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		if call < len(statuses) {
+			w.WriteHeader(statuses[call])
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func doRequest(t *testing.T, transport http.RoundTripper, url string) *http.Response {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/api/v1/monitor", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp
+}
+
+func TestCustomTransportRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     http.Header
+		statuses   []int
+		maxRetries int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"429 with reset header", http.Header{"X-Ratelimit-Reset": {"0"}}, []int{429, 429}, 10, 200, 3},
+		{"429 without headers", nil, []int{429}, 10, 200, 2},
+		{"503 with retry after", http.Header{"Retry-After": {"0"}}, []int{503}, 10, 200, 2},
+		{"500 until retries run out", nil, []int{500, 500, 500, 500}, 2, 500, 3},
+		{"400 is not retried", nil, []int{400}, 10, 400, 1},
+		{"404 is not retried", nil, []int{404}, 10, 404, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := serveStatuses(t, tt.header, tt.statuses...)
+			resp := doRequest(t, newTestTransport(tt.maxRetries), server.URL)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCustomTransportGivesUpAtDeadline(t *testing.T) {
+	server, calls := serveStatuses(t, http.Header{"Retry-After": {"60"}}, 429, 429)
+	timeout := time.Second
+	transport := NewCustomTransport(nil, CustomTransportOptions{Timeout: &timeout})
+
+	start := time.Now()
+	resp := doRequest(t, transport, server.URL)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed > timeout {
+		t.Errorf("gave up after %v, want less than %v", elapsed, timeout)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	transport := newTestTransport(10)
+	min, max := 100*time.Millisecond, time.Second
+
+	for attempt := 0; attempt < 10; attempt++ {
+		backoff := transport.DefaultBackoff(min, max, attempt, nil)
+		if backoff < min || backoff > max {
+			t.Errorf("attempt %d: backoff %v not within [%v, %v]", attempt, backoff, min, max)
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3"}}}
+	if got := transport.DefaultBackoff(min, max, 0, resp); got != 3*time.Second {
+		t.Errorf("backoff with Retry-After = %v, want 3s", got)
+	}
+}
+
+func TestRateLimitTrackerThrottles(t *testing.T) {
+	header := http.Header{
+		"X-Ratelimit-Limit":     {"2"},
+		"X-Ratelimit-Remaining": {"0"},
+		"X-Ratelimit-Period":    {"1"},
+		"X-Ratelimit-Reset":     {"1"},
+	}
+	server, calls := serveStatuses(t, header)
+	transport := newTestTransport(0)
+
+	doRequest(t, transport, server.URL)
+	start := time.Now()
+	doRequest(t, transport, server.URL)
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("second request was sent after %v, want it held back until the reset", elapsed)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("calls = %d, want 2", got)
+	}
+}
+
+func TestRateLimitTrackerIgnoresEmptyBuckets(t *testing.T) {
+	tracker := newRateLimitTracker()
+	for _, header := range []http.Header{
+		{"X-Ratelimit-Limit": {"0"}, "X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Period": {"10"}},
+		{"X-Ratelimit-Limit": {"10"}, "X-Ratelimit-Remaining": {"0"}},
+	} {
+		tracker.update("v1/monitor", &http.Response{StatusCode: http.StatusOK, Header: header})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		err := tracker.wait(ctx, "v1/monitor")
+		cancel()
+		if err != nil {
+			t.Errorf("headers %v held back the next request: %v", header, err)
+		}
+	}
+}
+
+func TestAPIFamilyOfPath(t *testing.T) {
+	tests := map[string]string{
+		"/api/v1/slo/search":            "slo",
+		"/api/v2/roles/abc/users":       "users",
+		"/api/v2/logs/events/search":    "logs_search",
+		"/api/v2/logs/config/metrics":   "logs_config",
+		"/api/v1/monitor":               "monitors",
+		"/api/v1/monitors":              "",
+		"/api/v2/services/definitions":  "",
+		"/api/v1/integration/aws":       "integrations",
+		"/api/v2/security_monitoring/x": "security_monitoring",
+	}
+	for path, want := range tests {
+		if got := apiFamilyOfPath(path); got != want {
+			t.Errorf("apiFamilyOfPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestFamilyLimiterThrottles(t *testing.T) {
+	server, calls := serveStatuses(t, nil)
+	limiters, err := newFamilyLimiters(map[string]float64{"monitors": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxRetries := 0
+	transport := NewCustomTransport(nil, CustomTransportOptions{MaxRetries: &maxRetries, FamilyLimiters: limiters})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		doRequest(t, transport, server.URL)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("3 requests at 2 per second took %v, want them throttled", elapsed)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestNewFamilyLimitersValidates(t *testing.T) {
+	if _, err := newFamilyLimiters(map[string]float64{"monitor": 1}); err == nil {
+		t.Error("want an error for an unknown API family")
+	}
+	if _, err := newFamilyLimiters(map[string]float64{"monitors": 0}); err == nil {
+		t.Error("want an error for a limit of 0")
+	}
+}
@@ -0,0 +1,162 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// queryStats counts the API calls of a query per endpoint, so slow queries can be
+// told apart by pagination, throttling or column hydrates. The stats are logged when
+// the query ends.
+type queryStats struct {
+	table   string
+	started time.Time
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointStats
+}
+
+// endpointStats are the API calls of a query to an endpoint, e.g. "GET /api/v1/slo/{id}".
+type endpointStats struct {
+	// Requests sent, including retries
+	Requests int64
+	// Requests sent again after a throttled (429) or failed (5xx) response
+	Retries int64
+	// Time waited before retries
+	BackoffMs int64
+	// Time requests were held back by the rate limits of the API and the rate_limits config
+	RateLimitWaitMs int64
+	// Time waiting for responses
+	DurationMs    int64
+	BytesSent     int64
+	BytesReceived int64
+}
+
+func (s *endpointStats) add(other *endpointStats) {
+	s.Requests += other.Requests
+	s.Retries += other.Retries
+	s.BackoffMs += other.BackoffMs
+	s.RateLimitWaitMs += other.RateLimitWaitMs
+	s.DurationMs += other.DurationMs
+	s.BytesSent += other.BytesSent
+	s.BytesReceived += other.BytesReceived
+}
+
+// queryStatsKey is the context key of the queryStats of the query a request is sent for.
+type queryStatsKey struct{}
+
+// runningQuery identifies a query by the done channel of its execute context, which
+// every hydrate call of the query derives its context from, and the connection, as
+// aggregator connections may share the context.
+type runningQuery struct {
+	done       <-chan struct{}
+	connection string
+}
+
+// runningQueryStats holds the stats of the running queries, so the hydrate calls of a
+// query all count to the same stats.
+var runningQueryStats sync.Map
+
+// runningQueryOf returns the key of the stats of the query the context belongs to.
+// Contexts which are never cancelled do not belong to a query.
+func runningQueryOf(ctx context.Context, d *plugin.QueryData) (runningQuery, bool) {
+	done := ctx.Done()
+	if done == nil || d.Connection == nil {
+		return runningQuery{}, false
+	}
+	return runningQuery{done: done, connection: d.Connection.Name}, true
+}
+
+// withQueryStats adds the stats of the query to the context, so the requests sent with
+// it are counted by CustomTransport. The stats are logged once, when the execute context
+// of the query is done, i.e. after its last row.
+func withQueryStats(ctx context.Context, d *plugin.QueryData) context.Context {
+	key, ok := runningQueryOf(ctx, d)
+	if !ok || d.Table == nil || ctx.Err() != nil {
+		// A call after the query ended would log its own partial stats
+		return ctx
+	}
+	stats := &queryStats{table: d.Table.Name, started: time.Now(), endpoints: map[string]*endpointStats{}}
+	if existing, loaded := runningQueryStats.LoadOrStore(key, stats); loaded {
+		stats = existing.(*queryStats)
+	} else {
+		logger := plugin.Logger(ctx)
+		context.AfterFunc(ctx, func() {
+			runningQueryStats.Delete(key)
+			if total := stats.total(); total.Requests > 0 {
+				logger.Info("datadog.queryStats", "table", stats.table, "connection", key.connection, "duration_ms", time.Since(stats.started).Milliseconds(), "requests", total.Requests, "retries", total.Retries, "backoff_ms", total.BackoffMs, "rate_limit_wait_ms", total.RateLimitWaitMs, "bytes_received", total.BytesReceived, "endpoints", stats.summary())
+			}
+		})
+	}
+	return context.WithValue(ctx, queryStatsKey{}, stats)
+}
+
+func queryStatsFromContext(ctx context.Context) *queryStats {
+	stats, _ := ctx.Value(queryStatsKey{}).(*queryStats)
+	return stats
+}
+
+// record adds the API calls to the endpoint to the stats. Nil stats, i.e. requests sent
+// outside of a query, are ignored.
+func (s *queryStats) record(endpoint string, calls endpointStats) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpoints[endpoint] == nil {
+		s.endpoints[endpoint] = &endpointStats{}
+	}
+	s.endpoints[endpoint].add(&calls)
+}
+
+func (s *queryStats) total() endpointStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total endpointStats
+	for _, calls := range s.endpoints {
+		total.add(calls)
+	}
+	return total
+}
+
+// summary lists the requests, retries and backoff per endpoint, e.g.
+// "GET /api/v1/monitor: 3 requests, 1 retries, 1000ms backoff".
+func (s *queryStats) summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoints := make([]string, 0, len(s.endpoints))
+	for endpoint := range s.endpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	lines := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		calls := s.endpoints[endpoint]
+		lines = append(lines, fmt.Sprintf("%s: %d requests, %d retries, %dms backoff", endpoint, calls.Requests, calls.Retries, calls.BackoffMs))
+	}
+	return strings.Join(lines, "; ")
+}
+
+// idSegment matches path segments which identify a resource, e.g. monitor IDs, UUIDs
+// and dashboard IDs like "abc-def-ghi".
+var idSegment = regexp.MustCompile(`\d|^[a-z0-9]{3}-[a-z0-9]{3}-[a-z0-9]{3}$`)
+
+// endpointName names the endpoint of a request for the stats, replacing the IDs in the
+// path after the resource, e.g. "GET /api/v1/slo/{id}", so calls per row add up.
+func endpointName(method, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 3; i < len(segments); i++ {
+		if idSegment.MatchString(segments[i]) {
+			segments[i] = "{id}"
+		}
+	}
+	return method + " /" + strings.Join(segments, "/")
+}
@@ -0,0 +1,86 @@
+package datadog
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+func TestEndpointName(t *testing.T) {
+	tests := map[string]string{
+		"/api/v1/monitor":                             "GET /api/v1/monitor",
+		"/api/v1/slo/slo-1":                           "GET /api/v1/slo/{id}",
+		"/api/v1/dashboard/abc-def-ghi":               "GET /api/v1/dashboard/{id}",
+		"/api/v2/security_monitoring/signals/search":  "GET /api/v2/security_monitoring/signals/search",
+		"/api/v2/roles/4b7d4c6e-1a2b/permissions":     "GET /api/v2/roles/{id}/permissions",
+		"/api/v2/users/00000000-0000-0000-0000-00001": "GET /api/v2/users/{id}",
+	}
+	for path, want := range tests {
+		if got := endpointName("GET", path); got != want {
+			t.Errorf("endpointName(%s) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestQueryStatsCountsRetries(t *testing.T) {
+	server, _ := serveStatuses(t, http.Header{"X-Ratelimit-Reset": {"0"}}, 429)
+	stats := &queryStats{table: "datadog_monitor", endpoints: map[string]*endpointStats{}}
+	ctx := context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+	ctx = context.WithValue(ctx, queryStatsKey{}, stats)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/monitor", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := newTestTransport(10).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	monitors := stats.endpoints["GET /api/v1/monitor"]
+	if monitors == nil {
+		t.Fatalf("endpoints = %v, want GET /api/v1/monitor", stats.endpoints)
+	}
+	if monitors.Requests != 2 || monitors.Retries != 1 {
+		t.Errorf("requests, retries = %d, %d, want 2, 1", monitors.Requests, monitors.Retries)
+	}
+	if monitors.BytesReceived <= 0 {
+		t.Errorf("bytes_received = %d, want > 0", monitors.BytesReceived)
+	}
+}
+
+func TestQueryStatsFlushedWhenQueryEnds(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/monitor", nil, fakeResponse{Fixture: "v1/monitors.json"})
+
+	if _, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}}); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	// The stats are flushed asynchronously once the context of the query is cancelled
+	deadline := time.Now().Add(time.Second)
+	for runningTestQueries() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if running := runningTestQueries(); running != 0 {
+		t.Errorf("%d queries still have stats after they ended, want 0", running)
+	}
+}
+
+func runningTestQueries() int {
+	running := 0
+	runningQueryStats.Range(func(key, _ interface{}) bool {
+		if strings.HasPrefix(key.(runningQuery).connection, "datadog_test_") {
+			running++
+		}
+		return true
+	})
+	return running
+}
@@ -0,0 +1,187 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	datadogV1 "github.com/DataDog/datadog-api-client-go/api/v1/datadog"
+	datadogV2 "github.com/DataDog/datadog-api-client-go/api/v2/datadog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// tableScopes lists the authorization scopes each table needs. Scoped application
+// keys and OAuth access tokens must be granted these scopes to query the table.
+// https://docs.datadoghq.com/account_management/rbac/permissions/
+var tableScopes = map[string][]string{
+	"datadog_dashboard":                  {"dashboards_read"},
+	"datadog_host":                       {"hosts_read"},
+	"datadog_integration_aws":            {"aws_configuration_read"},
+	"datadog_log_aggregate":              {"logs_read_data"},
+	"datadog_log_event":                  {"logs_read_data"},
+	"datadog_logs_metric":                {"logs_generate_metrics"},
+	"datadog_monitor":                    {"monitors_read"},
+	"datadog_permission":                 {"user_access_read"},
+	"datadog_role":                       {"user_access_read"},
+	"datadog_security_monitoring_rule":   {"security_monitoring_rules_read"},
+	"datadog_security_monitoring_signal": {"security_monitoring_signals_read"},
+	"datadog_service_level_objective":    {"slos_read"},
+	"datadog_user":                       {"user_access_read"},
+}
+
+// tableIgnoreErrorCodes lists the status codes each table ignores by default. Tables of
+// products that are not enabled for every org, or that need a product specific scope,
+// return 403 when the org or key lacks the entitlement, which should not fail queries
+// spanning several tables.
+var tableIgnoreErrorCodes = map[string][]int{
+	"datadog_security_monitoring_rule":   {http.StatusForbidden},
+	"datadog_security_monitoring_signal": {http.StatusForbidden},
+}
+
+// apiError is a failed Datadog API request, classified by its HTTP status and
+// described by the errors Datadog returned in the response body.
+type apiError struct {
+	StatusCode int
+	Status     string
+	Errors     []string
+	// hint tells how to resolve the error, e.g. which scopes are missing
+	hint string
+}
+
+func (e *apiError) Error() string {
+	msg := e.Status
+	if len(e.Errors) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(e.Errors, "; "))
+	}
+	if e.hint != "" {
+		msg = fmt.Sprintf("%s, %s", msg, e.hint)
+	}
+	return msg
+}
+
+// newAPIError builds the error of a failed response from its status and body.
+func newAPIError(statusCode int, status string, body []byte) *apiError {
+	return &apiError{
+		StatusCode: statusCode,
+		Status:     status,
+		Errors:     parseErrorBody(body),
+	}
+}
+
+// parseErrorBody returns the messages of the errors array of a Datadog error
+// response. V1 endpoints return plain strings while V2 endpoints return JSON:API
+// error objects, e.g. {"errors": [{"status": "404", "title": "Not Found", "detail": "..."}]}.
+func parseErrorBody(body []byte) []string {
+	var response struct {
+		Errors []json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil
+	}
+
+	messages := []string{}
+	for _, raw := range response.Errors {
+		var message string
+		if err := json.Unmarshal(raw, &message); err == nil {
+			messages = append(messages, message)
+			continue
+		}
+		var object struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		}
+		if err := json.Unmarshal(raw, &object); err == nil {
+			switch {
+			case object.Detail != "":
+				messages = append(messages, object.Detail)
+			case object.Title != "":
+				messages = append(messages, object.Title)
+			}
+		}
+	}
+	return messages
+}
+
+// toAPIError classifies a failed API request by the status of its response. httpResp
+// is the response the generated clients return along with the error, raw requests
+// pass nil as their errors already are *apiError. It returns nil if the request got
+// no failed response, e.g. on network errors.
+func toAPIError(httpResp *http.Response, err error) *apiError {
+	if err == nil {
+		return nil
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if httpResp == nil || httpResp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	var body []byte
+	switch e := err.(type) {
+	case datadogV1.GenericOpenAPIError:
+		body = e.Body()
+	case datadogV2.GenericOpenAPIError:
+		body = e.Body()
+	}
+	return newAPIError(httpResp.StatusCode, httpResp.Status, body)
+}
+
+// errorStatusCode returns the HTTP status code of a failed API request, or 0 if the
+// error is not an *apiError, see wrapAPIError.
+func errorStatusCode(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
+
+// isNotFoundError is used by the Get configs to return no row for items that do not exist.
+func isNotFoundError(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData, err error) bool {
+	return errorStatusCode(err) == http.StatusNotFound || shouldIgnoreErrors(ctx, d, h, err)
+}
+
+// shouldIgnoreErrors is the default ignore config of all tables. It ignores the status
+// codes of the ignore_error_codes config argument, or the defaults of the table when
+// the argument is not set.
+func shouldIgnoreErrors(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData, err error) bool {
+	statusCode := errorStatusCode(err)
+	if statusCode == 0 || d.Table == nil {
+		return false
+	}
+
+	codes := tableIgnoreErrorCodes[d.Table.Name]
+	if config := GetConfig(d.Connection); config.IgnoreErrorCodes != nil {
+		codes = config.IgnoreErrorCodes
+	}
+	for _, code := range codes {
+		if code == statusCode {
+			plugin.Logger(ctx).Warn("datadog.shouldIgnoreErrors", "table", d.Table.Name, "ignored_error", err)
+			return true
+		}
+	}
+	return false
+}
+
+// wrapAPIError turns errors of failed API requests into readable errors including
+// the messages returned by Datadog, classified by the status of httpResp so the ignore
+// configs can match them. 403 responses also name the scopes the queried table needs,
+// as Datadog does not report which scope is missing.
+func wrapAPIError(d *plugin.QueryData, httpResp *http.Response, err error) error {
+	apiErr := toAPIError(httpResp, err)
+	if apiErr == nil {
+		return err
+	}
+	if apiErr.StatusCode == http.StatusForbidden && d.Table != nil {
+		if scopes, ok := tableScopes[d.Table.Name]; ok {
+			wrapped := *apiErr
+			wrapped.hint = fmt.Sprintf("the api_key/app_key or access_token of the %s table must be granted the scope(s): %s", d.Table.Name, strings.Join(scopes, ", "))
+			return &wrapped
+		}
+	}
+	return apiErr
+}
@@ -0,0 +1,57 @@
+package datadog
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func TestParseErrorBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"v1 strings", `{"errors": ["Dashboard not found"]}`, []string{"Dashboard not found"}},
+		{"v2 objects", `{"errors": [{"status": "403", "title": "Forbidden", "detail": "missing permission"}, {"title": "Bad Request"}]}`, []string{"missing permission", "Bad Request"}},
+		{"not json", `<html>Bad Gateway</html>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseErrorBody([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseErrorBody() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	err := newAPIError(http.StatusBadRequest, "400 Bad Request", []byte(`{"errors": ["invalid query", "unknown facet"]}`))
+	if got, want := err.Error(), "400 Bad Request: invalid query; unknown facet"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got := errorStatusCode(err); got != http.StatusBadRequest {
+		t.Errorf("errorStatusCode() = %d, want 400", got)
+	}
+}
+
+func TestToAPIErrorUsesResponseStatus(t *testing.T) {
+	err := errors.New("unexpected error message")
+	apiErr := toAPIError(&http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found"}, err)
+	if apiErr == nil || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("toAPIError() = %v, want a 404", apiErr)
+	}
+	if got := errorStatusCode(wrapAPIError(&plugin.QueryData{}, &http.Response{StatusCode: http.StatusNotFound}, err)); got != http.StatusNotFound {
+		t.Errorf("errorStatusCode() of the wrapped error = %d, want 404", got)
+	}
+	// Responses which failed to decode are not failed requests
+	if apiErr := toAPIError(&http.Response{StatusCode: http.StatusOK}, err); apiErr != nil {
+		t.Errorf("toAPIError() of a 200 = %v, want nil", apiErr)
+	}
+	if apiErr := toAPIError(nil, err); apiErr != nil {
+		t.Errorf("toAPIError() without a response = %v, want nil", apiErr)
+	}
+}
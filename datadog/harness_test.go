@@ -0,0 +1,419 @@
+package datadog
+
+// Offline test harness: a fake Datadog server replays canned V1/V2 responses from
+// testdata, a recording transport captures every request the plugin sends, and
+// queries run in-process through the plugin server, so no test needs the network.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeResponse is a canned response of the fake Datadog server.
+type fakeResponse struct {
+	Status int
+	Header map[string]string
+	// Fixture is a file in testdata holding the response body, Body is used when it is empty
+	Fixture string
+	Body    string
+}
+
+// fakeRoute replays its responses in order, repeating the last one once all were served.
+type fakeRoute struct {
+	method    string
+	path      string
+	query     url.Values
+	responses []fakeResponse
+	calls     int
+}
+
+// fakeDatadog is a local Datadog API serving canned responses.
+type fakeDatadog struct {
+	t        *testing.T
+	server   *httptest.Server
+	recorder *recordingTransport
+	// proxy forwards the requests of the test connection through the recorder
+	proxy *httptest.Server
+
+	mu     sync.Mutex
+	routes []*fakeRoute
+	config string
+	// apiURLFromEnv leaves api_url out of the config, so it is resolved from the environment
+	apiURLFromEnv bool
+}
+
+// newFakeDatadog starts a fake Datadog server which accepts the keys of the test
+// connection. The test connection points at it through api_url, and sends its requests
+// through a proxy which passes them to the recorder, as sent by the plugin transport.
+func newFakeDatadog(t *testing.T) *fakeDatadog {
+	f := &fakeDatadog{t: t, recorder: &recordingTransport{base: &http.Transport{}}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.server.Close)
+	f.proxy = httptest.NewServer(http.HandlerFunc(f.forward))
+	t.Cleanup(f.proxy.Close)
+
+	// Keys validation of the connection, see getConnectionInfo
+	f.handle("GET", "/api/v1/validate", nil, fakeResponse{Body: `{"valid": true}`})
+	f.handle("GET", "/api/v1/org", nil, fakeResponse{Fixture: "v1/org.json"})
+	f.handle("GET", "/api/v2/current_user/application_keys", nil, fakeResponse{Body: `{"data": []}`})
+	return f
+}
+
+// handle registers the responses of requests with the method and path. When query is
+// set, only requests with these query parameters match. Later routes take precedence.
+func (f *fakeDatadog) handle(method, path string, query url.Values, responses ...fakeResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.routes = append([]*fakeRoute{{method: method, path: path, query: query, responses: responses}}, f.routes...)
+}
+
+// forward sends the request the proxy received through the recorder.
+func (f *fakeDatadog) forward(w http.ResponseWriter, r *http.Request) {
+	req := r.Clone(r.Context())
+	req.RequestURI = ""
+	resp, err := f.recorder.RoundTrip(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+func (f *fakeDatadog) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	response, ok := f.match(r)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"errors": ["no canned response for %s %s"]}`, r.Method, r.URL.Path)
+		return
+	}
+
+	body := []byte(response.Body)
+	if response.Fixture != "" {
+		var err error
+		body, err = os.ReadFile(filepath.Join("testdata", response.Fixture))
+		if err != nil {
+			f.t.Errorf("failed to read fixture: %v", err)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	for name, value := range response.Header {
+		w.Header().Set(name, value)
+	}
+	if response.Status != 0 {
+		w.WriteHeader(response.Status)
+	}
+	_, _ = w.Write(body)
+}
+
+func (f *fakeDatadog) match(r *http.Request) (fakeResponse, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, route := range f.routes {
+		if route.method != r.Method || route.path != r.URL.Path || !queryMatches(route.query, r.URL.Query()) {
+			continue
+		}
+		response := route.responses[len(route.responses)-1]
+		if route.calls < len(route.responses) {
+			response = route.responses[route.calls]
+		}
+		route.calls++
+		return response, true
+	}
+	return fakeResponse{}, false
+}
+
+func queryMatches(want, got url.Values) bool {
+	for name, values := range want {
+		if len(values) == 0 {
+			if got.Has(name) {
+				return false
+			}
+			continue
+		}
+		if got.Get(name) != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// recordedRequest is a request sent by the plugin and the status it got.
+type recordedRequest struct {
+	Method     string
+	URL        *url.URL
+	Header     http.Header
+	Body       []byte
+	StatusCode int
+}
+
+// recordingTransport records every request attempt sent through it.
+type recordingTransport struct {
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded := recordedRequest{Method: req.Method, URL: req.URL, Header: req.Header.Clone()}
+	if req.Body != nil {
+		recorded.Body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(recorded.Body))
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if resp != nil {
+		recorded.StatusCode = resp.StatusCode
+	}
+
+	r.mu.Lock()
+	r.requests = append(r.requests, recorded)
+	r.mu.Unlock()
+	return resp, err
+}
+
+// requestsTo returns the recorded requests to the path.
+func (r *recordingTransport) requestsTo(path string) []recordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var requests []recordedRequest
+	for _, req := range r.requests {
+		if req.URL.Path == path {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// testQuery describes a query against a table of the test connection.
+type testQuery struct {
+	Table   string
+	Columns []string
+	Quals   map[string]*proto.Quals
+	Limit   int64
+}
+
+var callID, connectionID int64
+
+// query runs the query in-process against a plugin configured for the fake server,
+// and returns the rows keyed by column name.
+func (f *fakeDatadog) query(q testQuery) ([]map[string]interface{}, error) {
+	f.t.Helper()
+
+	ctx := context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+	server := plugin.Server(&plugin.ServeOpts{PluginFunc: Plugin})
+
+	// Each plugin server gets its own connection name, so validations of the connection
+	// still running in the background of earlier queries are not shared
+	connection := fmt.Sprintf("datadog_test_%d", atomic.AddInt64(&connectionID, 1))
+	config := fmt.Sprintf(`
+api_key = "test-api-key"
+app_key = "test-app-key"
+min_error_retry_delay = 1
+max_error_retry_delay = 10
+%s
+`, f.config)
+	if !f.apiURLFromEnv {
+		config += fmt.Sprintf("api_url = \"%s/\"\nproxy_url = \"%s\"\n", f.server.URL, f.proxy.URL)
+	}
+	res, err := server.SetAllConnectionConfigs(&proto.SetAllConnectionConfigsRequest{
+		Configs: []*proto.ConnectionConfig{{
+			Connection: connection,
+			Plugin:     "datadog",
+			Config:     config,
+		}},
+		MaxCacheSizeMb: 16,
+	})
+	// Invalid configs fail the connection when it loads, report them like query errors
+	if err != nil {
+		return nil, err
+	}
+	if failed := res.FailedConnections[connection]; failed != "" {
+		return nil, errors.New(failed)
+	}
+
+	executeData := &proto.ExecuteConnectionData{}
+	if q.Limit > 0 {
+		executeData.Limit = &proto.NullableInt{Value: q.Limit}
+	}
+	req := &proto.ExecuteRequest{
+		Table: q.Table,
+		QueryContext: &proto.QueryContext{
+			Columns: q.Columns,
+			Quals:   q.Quals,
+		},
+		CallId:                fmt.Sprintf("test-%d", atomic.AddInt64(&callID, 1)),
+		ExecuteConnectionData: map[string]*proto.ExecuteConnectionData{connection: executeData},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	stream := &rowCollector{ctx: ctx}
+	err = server.Execute(req, stream)
+
+	var rows []map[string]interface{}
+	for _, resp := range stream.responses {
+		if resp == nil || resp.Row == nil {
+			continue
+		}
+		row := map[string]interface{}{}
+		for name, column := range resp.Row.Columns {
+			row[name] = columnValue(column)
+		}
+		rows = append(rows, row)
+	}
+	return rows, err
+}
+
+// rowCollector is the stream the plugin server sends the rows of a query to. Only
+// the methods used by the plugin server are implemented.
+type rowCollector struct {
+	proto.WrapperPlugin_ExecuteServer
+	ctx context.Context
+
+	mu        sync.Mutex
+	responses []*proto.ExecuteResponse
+}
+
+func (c *rowCollector) Send(resp *proto.ExecuteResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses = append(c.responses, resp)
+	return nil
+}
+
+func (c *rowCollector) Context() context.Context {
+	return c.ctx
+}
+
+// columnValue converts a column of a row to a Go value, JSON columns are decoded.
+func columnValue(column *proto.Column) interface{} {
+	switch v := column.Value.(type) {
+	case *proto.Column_StringValue:
+		return v.StringValue
+	case *proto.Column_IntValue:
+		return v.IntValue
+	case *proto.Column_DoubleValue:
+		return v.DoubleValue
+	case *proto.Column_BoolValue:
+		return v.BoolValue
+	case *proto.Column_TimestampValue:
+		return v.TimestampValue.AsTime().UTC()
+	case *proto.Column_JsonValue:
+		var value interface{}
+		_ = json.Unmarshal(v.JsonValue, &value)
+		return value
+	case *proto.Column_IpAddrValue:
+		return v.IpAddrValue
+	case *proto.Column_CidrRangeValue:
+		return v.CidrRangeValue
+	case *proto.Column_LtreeValue:
+		return v.LtreeValue
+	}
+	return nil
+}
+
+// stringQual builds the quals of a column compared to a string.
+func stringQual(column, operator, value string) map[string]*proto.Quals {
+	return map[string]*proto.Quals{
+		column: {Quals: []*proto.Qual{{
+			FieldName: column,
+			Operator:  &proto.Qual_StringValue{StringValue: operator},
+			Value:     &proto.QualValue{Value: &proto.QualValue_StringValue{StringValue: value}},
+		}}},
+	}
+}
+
+// listQual builds the quals of a column in a list of strings, as sent for IN.
+func listQual(column string, values ...string) map[string]*proto.Quals {
+	list := &proto.QualValueList{}
+	for _, value := range values {
+		list.Values = append(list.Values, &proto.QualValue{Value: &proto.QualValue_StringValue{StringValue: value}})
+	}
+	return map[string]*proto.Quals{
+		column: {Quals: []*proto.Qual{{
+			FieldName: column,
+			Operator:  &proto.Qual_StringValue{StringValue: "="},
+			Value:     &proto.QualValue{Value: &proto.QualValue_ListValue{ListValue: list}},
+		}}},
+	}
+}
+
+// jsonQual builds the quals of a JSON column compared to a JSON document.
+func jsonQual(column, value string) map[string]*proto.Quals {
+	return map[string]*proto.Quals{
+		column: {Quals: []*proto.Qual{{
+			FieldName: column,
+			Operator:  &proto.Qual_StringValue{StringValue: "="},
+			Value:     &proto.QualValue{Value: &proto.QualValue_JsonbValue{JsonbValue: value}},
+		}}},
+	}
+}
+
+// mergeQuals merges the quals of several columns.
+func mergeQuals(quals ...map[string]*proto.Quals) map[string]*proto.Quals {
+	merged := map[string]*proto.Quals{}
+	for _, q := range quals {
+		for column, columnQuals := range q {
+			merged[column] = columnQuals
+		}
+	}
+	return merged
+}
+
+// timestampQual builds the quals of a column compared to a timestamp.
+func timestampQual(column, operator string, value time.Time) map[string]*proto.Quals {
+	return map[string]*proto.Quals{
+		column: {Quals: []*proto.Qual{{
+			FieldName: column,
+			Operator:  &proto.Qual_StringValue{StringValue: operator},
+			Value:     &proto.QualValue{Value: &proto.QualValue_TimestampValue{TimestampValue: timestamppb.New(value)}},
+		}}},
+	}
+}
+
+// sortRows sorts the rows by a string column, as rows whose columns are hydrated
+// concurrently are not returned in the order they were listed.
+func sortRows(rows []map[string]interface{}, column string) []map[string]interface{} {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return fmt.Sprint(rows[i][column]) < fmt.Sprint(rows[j][column])
+	})
+	return rows
+}
+
+// columnValues returns the values of a column of the rows, in order.
+func columnValues(rows []map[string]interface{}, column string) []interface{} {
+	values := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		values = append(values, row[column])
+	}
+	return values
+}
@@ -0,0 +1,116 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/turbot/go-kit/helpers"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// datadogAppHosts maps the short name of each Datadog site to the host of its web app.
+var datadogAppHosts = map[string]string{
+	"us1": "app.datadoghq.com",
+	"us3": "us3.datadoghq.com",
+	"us5": "us5.datadoghq.com",
+	"eu1": "app.datadoghq.eu",
+	"ap1": "ap1.datadoghq.com",
+	"gov": "app.ddog-gov.com",
+}
+
+// siteItem is the item of a row together with the site of the connection, which the
+// akas and app_url columns are built from.
+type siteItem struct {
+	// The short name of the site, or the API host for custom API URLs
+	Site string
+	// The base URL of the web app of the site, nil if it cannot be told from the API URL
+	AppURL *url.URL
+	Item   interface{}
+}
+
+// resourceLink describes how the akas and app_url columns of a table are built from
+// the item of a row.
+type resourceLink struct {
+	// The resource type in the akas, e.g. "monitor"
+	Resource string
+	// The field of the item holding the ID of the resource
+	IDField string
+	// The path of the resource in the web app, with %s for the query escaped value
+	// of AppField, e.g. "/monitors/%s". No app_url is built when empty.
+	AppPath string
+	// The field of the item used in AppPath, IDField when empty
+	AppField string
+}
+
+func getSite(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	settings, err := getConnectionSettings(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog.getSite", "connection_error", err)
+		return nil, err
+	}
+	return &siteItem{Site: settings.site, AppURL: appURL(settings.apiURL), Item: h.Item}, nil
+}
+
+// appURL returns the base URL of the web app of the site the API URL belongs to. Other
+// API hosts are assumed to be named api.<domain> with the app at app.<domain>, and
+// hosts not following that pattern, e.g. proxies, get no app URL.
+func appURL(apiURL *url.URL) *url.URL {
+	if host, ok := datadogAppHosts[siteName(apiURL)]; ok {
+		return &url.URL{Scheme: "https", Host: host}
+	}
+	if domain, ok := strings.CutPrefix(apiURL.Host, "api."); ok {
+		return &url.URL{Scheme: apiURL.Scheme, Host: "app." + domain}
+	}
+	return nil
+}
+
+//// TRANSFORM FUNCTIONS
+
+// resourceAkas returns the datadog://<site>/<resource>/<id> URI of the item of the row,
+// given the siteItem as value and the resourceLink of the table as param.
+func resourceAkas(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	site := d.Value.(*siteItem)
+	link := d.Param.(resourceLink)
+	id := itemField(site.Item, link.IDField)
+	if id == "" {
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("datadog://%s/%s/%s", site.Site, link.Resource, id)}, nil
+}
+
+// resourceAppURL returns the link to the item of the row in the web app, given the
+// siteItem as value and the resourceLink of the table as param.
+func resourceAppURL(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	site := d.Value.(*siteItem)
+	link := d.Param.(resourceLink)
+	field := link.AppField
+	if field == "" {
+		field = link.IDField
+	}
+	value := itemField(site.Item, field)
+	if site.AppURL == nil || value == "" {
+		return nil, nil
+	}
+	return site.AppURL.String() + fmt.Sprintf(link.AppPath, url.QueryEscape(value)), nil
+}
+
+// itemField returns the value of the (nested) field of the item as a string, or an
+// empty string if it is not set.
+func itemField(item interface{}, field string) string {
+	value, ok := helpers.GetNestedFieldValueFromInterface(item, field)
+	if !ok || value == nil {
+		return ""
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,60 @@
+package datadog
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	datadogV1 "github.com/DataDog/datadog-api-client-go/api/v1/datadog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func TestAppURL(t *testing.T) {
+	tests := map[string]string{
+		"https://api.datadoghq.com/":     "https://app.datadoghq.com",
+		"https://api.us3.datadoghq.com/": "https://us3.datadoghq.com",
+		"https://api.datadoghq.eu/":      "https://app.datadoghq.eu",
+		"https://api.ddog-gov.com/":      "https://app.ddog-gov.com",
+		"https://api.dd.example.com/":    "https://app.dd.example.com",
+		"http://proxy.internal:8080/":    "",
+	}
+	for apiURL, want := range tests {
+		parsed, _ := url.Parse(apiURL)
+		got := ""
+		if u := appURL(parsed); u != nil {
+			got = u.String()
+		}
+		if got != want {
+			t.Errorf("appURL(%s) = %q, want %q", apiURL, got, want)
+		}
+	}
+}
+
+func TestResourceLinks(t *testing.T) {
+	id := int64(42)
+	name := "web 1.example.com"
+	host := datadogV1.Host{Id: &id, HostName: &name}
+	site := &siteItem{Site: "eu1", AppURL: &url.URL{Scheme: "https", Host: "app.datadoghq.eu"}, Item: host}
+
+	d := &transform.TransformData{Value: site, Param: hostLink}
+	akas, err := resourceAkas(context.Background(), d)
+	if err != nil || !reflect.DeepEqual(akas, []string{"datadog://eu1/host/42"}) {
+		t.Errorf("akas = %v, %v", akas, err)
+	}
+	appLink, err := resourceAppURL(context.Background(), d)
+	if err != nil || appLink != "https://app.datadoghq.eu/infrastructure?host=web+1.example.com" {
+		t.Errorf("app_url = %v, %v", appLink, err)
+	}
+
+	// Neither is built without the fields of the item or the app URL of the site
+	site = &siteItem{Site: "proxy:8080", Item: datadogV1.Host{}}
+	d = &transform.TransformData{Value: site, Param: hostLink}
+	if akas, _ := resourceAkas(context.Background(), d); akas != nil {
+		t.Errorf("akas = %v, want nil", akas)
+	}
+	site.Item = host
+	if appLink, _ := resourceAppURL(context.Background(), d); appLink != nil {
+		t.Errorf("app_url = %v, want nil", appLink)
+	}
+}
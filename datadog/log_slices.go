@@ -0,0 +1,101 @@
+package datadog
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// defaultLogSlices is the number of slices the time range of a log event search is
+// split into when the log_slices config argument is not set, i.e. it is not split.
+const defaultLogSlices = 1
+
+// defaultLogMaxConcurrency is the number of log searches a connection sends at once
+// when the log_max_concurrency config argument is not set. It matches the concurrency
+// of the rate limiter of the logs_search API family.
+const defaultLogMaxConcurrency = 2
+
+// minLogSliceDuration is the shortest slice of a time range, so short time ranges are
+// split into fewer slices than configured.
+const minLogSliceDuration = time.Minute
+
+// logTimeRange is the time range of a log search, from inclusive to exclusive.
+type logTimeRange struct {
+	from time.Time
+	to   time.Time
+}
+
+// logSlices splits the time range into the configured number of slices of equal
+// length. Time ranges without a start, which the API limits to the last 15 minutes,
+// are not split.
+func logSlices(d *plugin.QueryData, timeRange logTimeRange) []logTimeRange {
+	count := defaultLogSlices
+	if config := GetConfig(d.Connection); config.LogSlices != nil && *config.LogSlices > 0 {
+		count = *config.LogSlices
+	}
+	if timeRange.from.IsZero() || !timeRange.to.After(timeRange.from) {
+		return []logTimeRange{timeRange}
+	}
+	duration := timeRange.to.Sub(timeRange.from)
+	if maxCount := int(duration / minLogSliceDuration); count > maxCount {
+		count = maxCount
+	}
+	if count <= 1 {
+		return []logTimeRange{timeRange}
+	}
+
+	// The API has millisecond precision, so the boundaries are rounded to it
+	length := (duration / time.Duration(count)).Truncate(time.Millisecond)
+	slices := make([]logTimeRange, 0, count)
+	for i := 0; i < count; i++ {
+		slice := logTimeRange{from: timeRange.from.Add(time.Duration(i) * length), to: timeRange.from.Add(time.Duration(i+1) * length)}
+		if i == count-1 {
+			slice.to = timeRange.to
+		}
+		slices = append(slices, slice)
+	}
+	return slices
+}
+
+// logSearchSlotsMutex serializes creating the slots of a connection, so concurrent
+// log searches share them.
+var logSearchSlotsMutex sync.Mutex
+
+// acquireLogSearchSlot waits until the connection may send another log search, up to
+// log_max_concurrency at once across the queries of the connection. The returned func
+// releases the slot.
+func acquireLogSearchSlot(ctx context.Context, d *plugin.QueryData) (func(), error) {
+	slots := getLogSearchSlots(ctx, d)
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// getLogSearchSlots returns the slots of the log searches of the connection. They are
+// kept in the connection cache, which is cleared when the config changes, so a new
+// log_max_concurrency starts with new slots.
+func getLogSearchSlots(ctx context.Context, d *plugin.QueryData) chan struct{} {
+	logSearchSlotsMutex.Lock()
+	defer logSearchSlotsMutex.Unlock()
+
+	if cachedData, ok := d.ConnectionCache.Get(ctx, cacheKeyLogSearchSlots); ok {
+		return cachedData.(chan struct{})
+	}
+
+	size := defaultLogMaxConcurrency
+	if config := GetConfig(d.Connection); config.LogMaxConcurrency != nil && *config.LogMaxConcurrency > 0 {
+		size = *config.LogMaxConcurrency
+	}
+	slots := make(chan struct{}, size)
+
+	// The slots never expire, as new slots would let searches run beside those holding
+	// the old ones
+	_ = d.ConnectionCache.SetWithTTL(ctx, cacheKeyLogSearchSlots, slots, 0)
+
+	return slots
+}
@@ -0,0 +1,85 @@
+package datadog
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// defaultMaxConcurrency is the number of pages a table fetches at once when the
+// max_concurrency config argument is not set.
+const defaultMaxConcurrency = 5
+
+// getMaxConcurrency returns the number of pages the queried table may fetch at once,
+// which is the max_concurrency config argument capped by the limit of the table.
+func getMaxConcurrency(d *plugin.QueryData) int {
+	concurrency := defaultMaxConcurrency
+	if config := GetConfig(d.Connection); config.MaxConcurrency != nil && *config.MaxConcurrency > 0 {
+		concurrency = *config.MaxConcurrency
+	}
+	if d.Table != nil {
+		if limit, ok := tablePageConcurrency[d.Table.Name]; ok && limit < concurrency {
+			concurrency = limit
+		}
+	}
+	return concurrency
+}
+
+// lastPageNumber returns the number of the last page of a page number paginated
+// listing of total items, counting pages from 0.
+func lastPageNumber(total, pageSize int64) int64 {
+	if total <= 0 {
+		return 0
+	}
+	return (total - 1) / pageSize
+}
+
+// listPagesConcurrently fetches the pages from firstPage to lastPage with up to
+// max_concurrency requests at once, and hands them to streamPage in page order as
+// soon as they and all pages before them have arrived. streamPage returns false to
+// stop early, e.g. once the limit of the query is hit, which cancels the outstanding
+// requests.
+func listPagesConcurrently[T any](ctx context.Context, d *plugin.QueryData, firstPage, lastPage int64, fetchPage func(ctx context.Context, page int64) (T, error), streamPage func(page T) bool) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		page T
+		err  error
+	}
+
+	// The queue of pages being fetched, in page order. A page is queued before its
+	// request is sent, so the queue capacity bounds the number of requests in flight.
+	concurrency := getMaxConcurrency(d)
+	queue := make(chan chan result, concurrency-1)
+
+	go func() {
+		defer close(queue)
+		for page := firstPage; page <= lastPage; page++ {
+			pending := make(chan result, 1)
+			select {
+			case queue <- pending:
+			case <-ctx.Done():
+				return
+			}
+
+			// Respect the rate limiters of the list call declared by the plugin
+			d.WaitForListRateLimit(ctx)
+			go func(page int64) {
+				resp, err := fetchPage(ctx, page)
+				pending <- result{page: resp, err: err}
+			}(page)
+		}
+	}()
+
+	for pending := range queue {
+		r := <-pending
+		if r.err != nil {
+			return r.err
+		}
+		if !streamPage(r.page) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -5,8 +5,17 @@ import (
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"github.com/turbot/steampipe-plugin-sdk/v5/rate_limiter"
 )
 
+// tablePageConcurrency caps the max_concurrency of the tables which fetch their pages
+// concurrently, so a single query does not use up the rate limit of its API family.
+var tablePageConcurrency = map[string]int{
+	"datadog_role":                     5,
+	"datadog_security_monitoring_rule": 5,
+	"datadog_user":                     5,
+}
+
 func Plugin(ctx context.Context) *plugin.Plugin {
 	p := &plugin.Plugin{
 		Name: "steampipe-plugin-datadog",
@@ -14,10 +23,33 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			NewInstance: ConfigInstance,
 		},
 		DefaultTransform: transform.FromCamel(),
+		DefaultIgnoreConfig: &plugin.IgnoreConfig{
+			ShouldIgnoreErrorFunc: shouldIgnoreErrors,
+		},
+		// Lets aggregator queries filtering on the org skip the connections of other orgs
+		ConnectionKeyColumns: []plugin.ConnectionKeyColumn{
+			{
+				Name:    "org_public_id",
+				Hydrate: getOrgPublicID,
+			},
+		},
+		RateLimiters: append(apiFamilyRateLimiters(),
+			// The page requests of the list calls tagged as concurrently paginated, see listPagesConcurrently
+			&rate_limiter.Definition{
+				Name:       "datadog_list_pages",
+				FillRate:   10,
+				BucketSize: 10,
+				Scope:      []string{"connection", "table"},
+				Where:      "pagination = 'concurrent'",
+			},
+		),
 		TableMap: map[string]*plugin.Table{
+			"datadog_api_request":                tableDatadogAPIRequest(ctx),
+			"datadog_connection_info":            tableDatadogConnectionInfo(ctx),
 			"datadog_dashboard":                  tableDatadogDashboard(ctx),
 			"datadog_host":                       tableDatadogHost(ctx),
 			"datadog_integration_aws":            tableDatadogIntegrationAws(ctx),
+			"datadog_log_aggregate":              tableDatadogLogAggregate(ctx),
 			"datadog_log_event":                  tableDatadogLogEvent(ctx),
 			"datadog_logs_metric":                tableDatadogLogsMetric(ctx),
 			"datadog_monitor":                    tableDatadogMonitor(ctx),
@@ -0,0 +1,23 @@
+package datadog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+func TestTablesDoNotRateLimitLocalHydrates(t *testing.T) {
+	ctx := context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+	tables := Plugin(ctx).TableMap
+
+	for name, table := range tables {
+		if _, ok := table.Tags["api_family"]; ok {
+			t.Errorf("%s: api_family is a table tag, which rate limits local hydrates like getSite", name)
+		}
+		if table.List != nil && table.List.Tags["api_family"] == "" && tableScopes[name] != nil {
+			t.Errorf("%s: list calls are not tagged with their API family", name)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/rate_limiter"
+	"golang.org/x/time/rate"
+)
+
+// https://docs.datadoghq.com/api/latest/rate-limits/
+var (
+	rateLimitLimitHeader     = "X-Ratelimit-Limit"
+	rateLimitPeriodHeader    = "X-Ratelimit-Period"
+	rateLimitRemainingHeader = "X-Ratelimit-Remaining"
+	rateLimitNameHeader      = "X-Ratelimit-Name"
+)
+
+// rateLimitTracker keeps track of the rate limit budget that Datadog reports in the
+// X-RateLimit-* response headers, and throttles outgoing requests of a bucket once
+// its budget is used up, instead of waiting for the API to return 429 errors.
+type rateLimitTracker struct {
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+}
+
+// rateLimitBucket is the latest known state of a rate limit bucket.
+type rateLimitBucket struct {
+	name      string
+	limit     int64
+	remaining int64
+	period    time.Duration
+	resetAt   time.Time
+}
+
+func newRateLimitTracker() *rateLimitTracker {
+	return &rateLimitTracker{
+		buckets: map[string]*rateLimitBucket{},
+	}
+}
+
+// rateLimitFamily returns the endpoint family of a request, which is used as the
+// bucket key, e.g. "v1/slo" for both "/api/v1/slo/search" and "/api/v1/slo/abc123".
+func rateLimitFamily(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) > 0 && segments[0] == "api" {
+		segments = segments[1:]
+	}
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	return strings.Join(segments, "/")
+}
+
+// wait blocks until the bucket of the family has budget left, and reserves one request from it.
+func (r *rateLimitTracker) wait(ctx context.Context, family string) error {
+	for {
+		r.mu.Lock()
+		bucket, ok := r.buckets[family]
+		if !ok {
+			r.mu.Unlock()
+			return nil
+		}
+
+		now := time.Now()
+		if !now.Before(bucket.resetAt) {
+			// The period has rolled over, assume a full budget until the API tells otherwise
+			bucket.remaining = bucket.limit
+			bucket.resetAt = now.Add(bucket.period)
+		}
+		if bucket.remaining > 0 {
+			bucket.remaining--
+			r.mu.Unlock()
+			return nil
+		}
+		delay := bucket.resetAt.Sub(now)
+		name := bucket.name
+		r.mu.Unlock()
+
+		plugin.Logger(ctx).Debug("datadog.rateLimitTracker.wait", "family", family, "bucket", name, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// update records the rate limit headers of a response against the bucket of the family.
+func (r *rateLimitTracker) update(family string, resp *http.Response) {
+	limit, err := strconv.ParseInt(resp.Header.Get(rateLimitLimitHeader), 10, 64)
+	if err != nil {
+		return
+	}
+	remaining, err := strconv.ParseInt(resp.Header.Get(rateLimitRemainingHeader), 10, 64)
+	if err != nil {
+		return
+	}
+	period, _ := strconv.ParseInt(resp.Header.Get(rateLimitPeriodHeader), 10, 64)
+	if limit <= 0 || period <= 0 {
+		// A bucket without budget or period would hold back requests forever, or not at all
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(rateLimitResetHeader), 10, 64)
+	if err != nil {
+		reset = period
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		remaining = 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buckets[family] = &rateLimitBucket{
+		name:      resp.Header.Get(rateLimitNameHeader),
+		limit:     limit,
+		remaining: remaining,
+		period:    time.Duration(period) * time.Second,
+		resetAt:   time.Now().Add(time.Duration(reset) * time.Second),
+	}
+}
+
+// apiFamily is a group of Datadog endpoints sharing a rate limit.
+type apiFamily struct {
+	// The path prefixes of the endpoints of the family
+	paths []string
+	// The default limits of the calls of the family, per connection
+	fillRate       rate.Limit
+	bucketSize     int64
+	maxConcurrency int64
+}
+
+// apiFamilies lists the API families of the tables. Tables tag their calls with the
+// family in the api_family tag, which selects the rate limiter of the family, and the
+// rate_limits config argument further limits the requests of a family per connection.
+var apiFamilies = map[string]apiFamily{
+	"dashboards":          {paths: []string{"/api/v1/dashboard"}, fillRate: 10, bucketSize: 10, maxConcurrency: 10},
+	"hosts":               {paths: []string{"/api/v1/hosts"}, fillRate: 5, bucketSize: 5, maxConcurrency: 5},
+	"integrations":        {paths: []string{"/api/v1/integration"}, fillRate: 5, bucketSize: 5, maxConcurrency: 5},
+	"logs_config":         {paths: []string{"/api/v2/logs/config"}, fillRate: 10, bucketSize: 10, maxConcurrency: 5},
+	"logs_search":         {paths: []string{"/api/v2/logs/events", "/api/v2/logs/analytics"}, fillRate: 2, bucketSize: 4, maxConcurrency: 2},
+	"monitors":            {paths: []string{"/api/v1/monitor"}, fillRate: 10, bucketSize: 10, maxConcurrency: 10},
+	"security_monitoring": {paths: []string{"/api/v2/security_monitoring"}, fillRate: 10, bucketSize: 10, maxConcurrency: 10},
+	"slo":                 {paths: []string{"/api/v1/slo"}, fillRate: 10, bucketSize: 10, maxConcurrency: 10},
+	"users":               {paths: []string{"/api/v2/users", "/api/v2/roles", "/api/v2/permissions"}, fillRate: 10, bucketSize: 10, maxConcurrency: 10},
+}
+
+// apiFamilyTags returns the rate limiter tags of the calls of a family. Only the
+// configs of hydrates calling the API are tagged, never the table, as table tags also
+// apply to local hydrates like getSite, which would then wait for the limiters.
+func apiFamilyTags(family string) map[string]string {
+	return map[string]string{"api_family": family}
+}
+
+// apiFamilyRateLimiters returns a rate limiter per API family, scoped by connection,
+// in a stable order. Steampipe limiter blocks can override them by name.
+func apiFamilyRateLimiters() []*rate_limiter.Definition {
+	names := make([]string, 0, len(apiFamilies))
+	for name := range apiFamilies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	definitions := make([]*rate_limiter.Definition, 0, len(names))
+	for _, name := range names {
+		family := apiFamilies[name]
+		definitions = append(definitions, &rate_limiter.Definition{
+			Name:           "datadog_" + name,
+			FillRate:       family.fillRate,
+			BucketSize:     family.bucketSize,
+			MaxConcurrency: family.maxConcurrency,
+			Scope:          []string{"connection", "api_family"},
+			Where:          fmt.Sprintf("api_family = '%s'", name),
+		})
+	}
+	return definitions
+}
+
+// apiFamilyOfPath returns the API family of an endpoint, or "" if it belongs to none.
+func apiFamilyOfPath(path string) string {
+	for name, family := range apiFamilies {
+		for _, prefix := range family.paths {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// validateRateLimits checks the rate_limits config argument, which maps API families to
+// the requests per second allowed for the connection. A rate of 0 would never allow a
+// request and an infinite rate would not limit anything, so both are rejected.
+func validateRateLimits(rateLimits map[string]float64) error {
+	for name, requestsPerSecond := range rateLimits {
+		if _, ok := apiFamilies[name]; !ok {
+			names := make([]string, 0, len(apiFamilies))
+			for name := range apiFamilies {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("invalid API family %q in rate_limits, must be one of: %s", name, strings.Join(names, ", "))
+		}
+		if !(requestsPerSecond > 0) || math.IsInf(requestsPerSecond, 1) {
+			return fmt.Errorf("rate_limits of %s must be a finite number of requests per second greater than 0, got %v", name, requestsPerSecond)
+		}
+	}
+	return nil
+}
+
+// newFamilyLimiters builds the limiters of the rate_limits config argument.
+func newFamilyLimiters(rateLimits map[string]float64) (map[string]*rate.Limiter, error) {
+	if err := validateRateLimits(rateLimits); err != nil {
+		return nil, err
+	}
+	limiters := map[string]*rate.Limiter{}
+	for name, requestsPerSecond := range rateLimits {
+		limiters[name] = rate.NewLimiter(rate.Limit(requestsPerSecond), int(math.Max(1, math.Ceil(requestsPerSecond))))
+	}
+	return limiters, nil
+}
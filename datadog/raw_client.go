@@ -0,0 +1,222 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	connectionmanager "github.com/turbot/steampipe-plugin-sdk/v5/connection"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// rawClient sends requests to Datadog endpoints missing from, or broken in, the
+// generated clients. It shares the base URL, authentication and transport of the
+// connection with the generated clients, so requests are retried and rate limited
+// the same way.
+type rawClient struct {
+	httpClient *http.Client
+	settings   *connectionSettings
+	// The stats of the query the client is used for, nil outside of queries
+	stats *queryStats
+}
+
+// connectRaw returns the raw endpoint client of the connection.
+func connectRaw(ctx context.Context, d *plugin.QueryData) (*rawClient, error) {
+	ctx = withQueryStats(ctx, d)
+
+	settings, err := getConnectionSettings(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail fast with a clear message if the keys are rejected by Datadog
+	if _, err := getConnectionInfo(ctx, d.Connection, d.ConnectionCache); err != nil {
+		return nil, err
+	}
+
+	client, err := getRawClient(ctx, d.Connection, d.ConnectionCache, settings)
+	if err != nil {
+		return nil, err
+	}
+	client.stats = queryStatsFromContext(ctx)
+	return client, nil
+}
+
+// getRawClient returns the raw endpoint client of the connection without validating
+// the keys, for use during validation itself.
+func getRawClient(ctx context.Context, connection *plugin.Connection, cache *connectionmanager.ConnectionCache, settings *connectionSettings) (*rawClient, error) {
+	clientCacheMutex.Lock()
+	defer clientCacheMutex.Unlock()
+
+	httpClient, err := getHTTPClient(ctx, connection, cache)
+	if err != nil {
+		return nil, err
+	}
+	return &rawClient{httpClient: httpClient, settings: settings}, nil
+}
+
+// do sends a request to the path of the API, e.g. "/api/v1/slo/search". The body, if
+// any, is sent as JSON and the response is decoded into out, unless out is nil.
+// Failed responses are returned as *apiError.
+func (c *rawClient) do(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}) error {
+	endpoint := c.endpoint(path)
+	endpoint.RawQuery = query.Encode()
+	return c.send(ctx, method, endpoint, body, out)
+}
+
+// endpoint returns the URL of the path of the API, below the path of the api_url.
+func (c *rawClient) endpoint(path string) *url.URL {
+	return &url.URL{Scheme: c.settings.apiURL.Scheme, Host: c.settings.apiURL.Host, Path: c.settings.apiBasePath() + path}
+}
+
+// resolve resolves a link returned by the API, e.g. the links.next URL of a JSON:API
+// response. Links to other hosts are rejected, so the keys are never sent elsewhere.
+func (c *rawClient) resolve(link string) (*url.URL, error) {
+	ref, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	resolved := c.endpoint("/").ResolveReference(ref)
+	if resolved.Host != c.settings.apiURL.Host {
+		return nil, fmt.Errorf("refusing to follow link to %s, which is not the API host %s", resolved.Host, c.settings.apiURL.Host)
+	}
+	// Links are relative to the root of the API, which is below the path of the api_url
+	if basePath := c.settings.apiBasePath(); basePath != "" && !strings.HasPrefix(resolved.Path, basePath+"/") {
+		resolved.Path = basePath + resolved.Path
+		resolved.RawPath = ""
+	}
+	return resolved, nil
+}
+
+// send sends a request to the URL, see do.
+func (c *rawClient) send(ctx context.Context, method string, endpoint *url.URL, body interface{}, out interface{}) error {
+	path := endpoint.Path
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	if c.stats != nil {
+		ctx = context.WithValue(ctx, queryStatsKey{}, c.stats)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Steampipe")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	setAuthHeaders(req, c.settings)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return newAPIError(resp.StatusCode, resp.Status, respBody)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode the response of %s %s: %v", method, path, err)
+	}
+	return nil
+}
+
+// get sends a GET request to the path and decodes the response into out.
+func (c *rawClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// jsonAPIPage is a page of a JSON:API list response, as returned by most V2 endpoints.
+type jsonAPIPage struct {
+	Data []json.RawMessage `json:"data"`
+	Meta struct {
+		Page struct {
+			After *string `json:"after"`
+		} `json:"page"`
+	} `json:"meta"`
+}
+
+// pageNumberPagination is the pagination metadata of page number paginated responses.
+type pageNumberPagination struct {
+	Number     *int `json:"number"`
+	LastNumber *int `json:"last_number"`
+	Total      *int `json:"total"`
+}
+
+// hasNext reports whether there is a page after the current one.
+func (p *pageNumberPagination) hasNext() bool {
+	return p != nil && p.Number != nil && p.LastNumber != nil && *p.Number < *p.LastNumber
+}
+
+// listPageNumbers requests the pages of a page number paginated endpoint, starting at
+// page 0 and passing the page as page[number] and page[size]. Each page is decoded into
+// a new T and handed to handlePage, which returns false to stop, e.g. once the last
+// page was seen or the query limit was hit.
+func listPageNumbers[T any](ctx context.Context, c *rawClient, path string, query url.Values, pageSize int, handlePage func(page *T) (bool, error)) error {
+	params := cloneValues(query)
+	params.Set("page[size]", fmt.Sprint(pageSize))
+	for pageNumber := 0; ; pageNumber++ {
+		params.Set("page[number]", fmt.Sprint(pageNumber))
+
+		page := new(T)
+		if err := c.get(ctx, path, params, page); err != nil {
+			return err
+		}
+		more, err := handlePage(page)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// listJSONAPICursor requests the pages of a cursor paginated JSON:API endpoint. The
+// cursor of the next page, meta.page.after, is passed as cursorParam, e.g. page[cursor].
+// handlePage returns false to stop before the last page.
+func (c *rawClient) listJSONAPICursor(ctx context.Context, path string, query url.Values, cursorParam string, handlePage func(page *jsonAPIPage) (bool, error)) error {
+	params := cloneValues(query)
+	for {
+		page := &jsonAPIPage{}
+		if err := c.get(ctx, path, params, page); err != nil {
+			return err
+		}
+		more, err := handlePage(page)
+		if err != nil || !more {
+			return err
+		}
+
+		after := page.Meta.Page.After
+		if after == nil || *after == "" || len(page.Data) == 0 {
+			return nil
+		}
+		params.Set(cursorParam, *after)
+	}
+}
+
+func cloneValues(values url.Values) url.Values {
+	clone := url.Values{}
+	for name, value := range values {
+		clone[name] = append([]string(nil), value...)
+	}
+	return clone
+}
@@ -0,0 +1,145 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func newTestRawClient(t *testing.T, f *fakeDatadog) *rawClient {
+	apiURL, err := url.Parse(f.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &rawClient{
+		httpClient: &http.Client{Transport: f.recorder},
+		settings:   &connectionSettings{authType: authTypeAPIKey, apiKey: "test-api-key", appKey: "test-app-key", apiURL: apiURL},
+	}
+}
+
+func TestRawClientCursorPagination(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/things", url.Values{"page[cursor]": nil}, fakeResponse{Body: `{"data": [{"id": "a"}, {"id": "b"}], "meta": {"page": {"after": "next"}}}`})
+	f.handle("GET", "/api/v2/things", url.Values{"page[cursor]": {"next"}}, fakeResponse{Body: `{"data": [{"id": "c"}], "meta": {"page": {}}}`})
+	client := newTestRawClient(t, f)
+
+	var ids []string
+	err := client.listJSONAPICursor(context.Background(), "/api/v2/things", url.Values{"filter": {"x"}}, "page[cursor]", func(page *jsonAPIPage) (bool, error) {
+		for _, item := range page.Data {
+			ids = append(ids, string(item))
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("listJSONAPICursor failed: %v", err)
+	}
+	if want := []string{`{"id": "a"}`, `{"id": "b"}`, `{"id": "c"}`}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("items = %v, want %v", ids, want)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/things")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	for _, req := range requests {
+		if req.URL.Query().Get("filter") != "x" {
+			t.Errorf("request %s lost the filter parameter", req.URL)
+		}
+		if req.Header.Get("DD-API-KEY") != "test-api-key" || req.Header.Get("DD-APPLICATION-KEY") != "test-app-key" {
+			t.Errorf("request %s is not authenticated", req.URL)
+		}
+	}
+}
+
+func TestRawClientPageNumbers(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/things", url.Values{"page[number]": {"0"}}, fakeResponse{Body: `{"meta": {"pagination": {"number": 0, "last_number": 1}}}`})
+	f.handle("GET", "/api/v1/things", url.Values{"page[number]": {"1"}}, fakeResponse{Body: `{"meta": {"pagination": {"number": 1, "last_number": 1}}}`})
+	client := newTestRawClient(t, f)
+
+	type thingsPage struct {
+		Meta struct {
+			Pagination *pageNumberPagination `json:"pagination"`
+		} `json:"meta"`
+	}
+	pages := 0
+	err := listPageNumbers(context.Background(), client, "/api/v1/things", nil, 50, func(page *thingsPage) (bool, error) {
+		pages++
+		return page.Meta.Pagination.hasNext(), nil
+	})
+	if err != nil {
+		t.Fatalf("listPageNumbers failed: %v", err)
+	}
+	if pages != 2 {
+		t.Errorf("pages = %d, want 2", pages)
+	}
+	requests := f.recorder.requestsTo("/api/v1/things")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := requests[0].URL.Query().Get("page[size]"); got != "50" {
+		t.Errorf("page[size] = %q, want 50", got)
+	}
+}
+
+func TestRawClientError(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/things", nil, fakeResponse{Status: http.StatusBadRequest, Body: `{"errors": [{"title": "Bad Request", "detail": "invalid filter"}]}`})
+	client := newTestRawClient(t, f)
+
+	err := client.get(context.Background(), "/api/v2/things", nil, &jsonAPIPage{})
+	apiErr, ok := err.(*apiError)
+	if !ok {
+		t.Fatalf("error = %#v, want *apiError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || !reflect.DeepEqual(apiErr.Errors, []string{"invalid filter"}) {
+		t.Errorf("error = %v", apiErr)
+	}
+}
+
+func TestListSLOsWithAPIURLFromEnv(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/slo/search", nil, fakeResponse{Fixture: "v1/slo_search_page1.json"})
+	f.apiURLFromEnv = true
+	t.Setenv("DD_CLIENT_API_URL", f.server.URL)
+
+	rows, err := f.query(testQuery{Table: "datadog_service_level_objective", Columns: []string{"id"}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["id"] != "slo-2" {
+		t.Errorf("rows = %v, want slo-2", rows)
+	}
+}
+
+func TestAPIURLPath(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.apiURLFromEnv = true
+	f.config = fmt.Sprintf("api_url = \"%s/datadog/\"\nproxy_url = \"%s\"", f.server.URL, f.proxy.URL)
+	f.handle("GET", "/datadog/api/v1/validate", nil, fakeResponse{Body: `{"valid": true}`})
+	f.handle("GET", "/datadog/api/v1/org", nil, fakeResponse{Fixture: "v1/org.json"})
+	f.handle("GET", "/datadog/api/v2/current_user/application_keys", nil, fakeResponse{Body: `{"data": []}`})
+	f.handle("GET", "/datadog/api/v2/permissions", nil, fakeResponse{Fixture: "v2/permissions.json"})
+	f.handle("GET", "/datadog/api/v2/services", nil, fakeResponse{Body: `{"data": [{"id": "a"}], "links": {"next": "/api/v2/services?page=2"}}`})
+	f.handle("GET", "/datadog/api/v2/services", url.Values{"page": {"2"}}, fakeResponse{Body: `{"data": [{"id": "b"}]}`})
+
+	if _, err := f.query(testQuery{Table: "datadog_permission", Columns: []string{"id"}}); err != nil {
+		t.Fatalf("datadog_permission query failed: %v", err)
+	}
+	rows, err := f.query(testQuery{Table: "datadog_api_request", Columns: []string{"item"}, Quals: stringQual("path", "=", "/api/v2/services")})
+	if err != nil {
+		t.Fatalf("datadog_api_request query failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("got %d rows, want the 2 items of both pages", len(rows))
+	}
+	if got := len(f.recorder.requestsTo("/datadog/api/v2/permissions")); got != 1 {
+		t.Errorf("got %d permission requests below the path of the api_url, want 1", got)
+	}
+	if got := len(f.recorder.requestsTo("/datadog/api/v2/services")); got != 2 {
+		t.Errorf("got %d service requests below the path of the api_url, want 2", got)
+	}
+}
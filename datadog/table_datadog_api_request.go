@@ -0,0 +1,251 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableDatadogAPIRequest(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "datadog_api_request",
+		Description: "Send a GET request to any read-only endpoint of the Datadog API, returning a row per item of the response.",
+		List: &plugin.ListConfig{
+			Hydrate: listAPIRequest,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "path", Require: plugin.Required},
+				{Name: "params", Require: plugin.Optional},
+				{Name: "items_path", Require: plugin.Optional},
+			},
+		},
+		Columns: commonColumns([]*plugin.Column{
+			// Top columns
+			{Name: "path", Type: proto.ColumnType_STRING, Transform: transform.FromQual("path"), Description: "The path of the endpoint, e.g. /api/v2/users."},
+			{Name: "params", Type: proto.ColumnType_JSON, Transform: transform.FromQual("params"), Description: "The query parameters of the request, as an object of names to values or arrays of values."},
+			{Name: "items_path", Type: proto.ColumnType_STRING, Transform: transform.FromQual("items_path"), Description: "The dot separated path of the array of the response to return a row per element of, e.g. data.attributes.slos. Defaults to the data array or the response itself if it is an array. Use . to return the whole response as a single row."},
+			{Name: "page", Type: proto.ColumnType_INT, Description: "The page of the response the item is on, starting at 0."},
+			{Name: "item_index", Type: proto.ColumnType_INT, Transform: transform.FromField("Index"), Description: "The position of the item across all pages, starting at 0."},
+
+			// JSON columns
+			{Name: "item", Type: proto.ColumnType_JSON, Description: "The item of the response."},
+		}),
+	}
+}
+
+// apiRequestItem is an item of the response of an arbitrary endpoint.
+type apiRequestItem struct {
+	Page  int
+	Index int
+	Item  json.RawMessage
+}
+
+// apiRequestPageHints holds the pagination metadata of the common Datadog pagination
+// styles: JSON:API next links, JSON:API cursors and page numbers.
+type apiRequestPageHints struct {
+	Meta struct {
+		Page struct {
+			After *string `json:"after"`
+		} `json:"page"`
+		Pagination *pageNumberPagination `json:"pagination"`
+	} `json:"meta"`
+	Links struct {
+		Next *string `json:"next"`
+	} `json:"links"`
+}
+
+func listAPIRequest(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	path := d.EqualsQualString("path")
+	// Only API paths are allowed, so the keys are never sent to other URLs
+	if !strings.HasPrefix(path, "/api/") {
+		return nil, fmt.Errorf("path must be an API path starting with /api/, e.g. /api/v2/users, got %q", path)
+	}
+	query, err := apiRequestParams(d.EqualsQuals["params"].GetJsonbValue())
+	if err != nil {
+		return nil, err
+	}
+	// A query string in the path is sent along with the params, which take precedence
+	if rawPath, rawQuery, ok := strings.Cut(path, "?"); ok {
+		pathQuery, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query string in path %q: %v", path, err)
+		}
+		for name, values := range pathQuery {
+			if !query.Has(name) {
+				query[name] = values
+			}
+		}
+		path = rawPath
+	}
+	itemsPath := d.EqualsQualString("items_path")
+
+	client, err := connectRaw(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_api_request.listAPIRequest", "connection_error", err)
+		return nil, err
+	}
+
+	endpoint := client.endpoint(path)
+	endpoint.RawQuery = query.Encode()
+	index := 0
+	for page := 0; endpoint != nil; page++ {
+		var body json.RawMessage
+		if err := client.send(ctx, http.MethodGet, endpoint, nil, &body); err != nil {
+			plugin.Logger(ctx).Error("datadog_api_request.listAPIRequest", "query_error", err, "path", path)
+			return nil, wrapAPIError(d, nil, err)
+		}
+
+		items, err := selectItems(body, itemsPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			d.StreamListItem(ctx, apiRequestItem{Page: page, Index: index, Item: item})
+			index++
+
+			// Check if context has been cancelled or if the limit has been hit (if specified)
+			if d.RowsRemaining(ctx) == 0 {
+				return nil, nil
+			}
+		}
+
+		if len(items) == 0 {
+			break
+		}
+		endpoint, err = nextPageURL(client, endpoint, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// apiRequestParams converts the params qual to query parameters. Values may be strings,
+// numbers, booleans or arrays of these, which repeat the parameter.
+func apiRequestParams(params string) (url.Values, error) {
+	query := url.Values{}
+	if params == "" {
+		return query, nil
+	}
+
+	var values map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(params))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("params must be a JSON object of query parameters: %v", err)
+	}
+	for name, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, element := range v {
+				query.Add(name, fmt.Sprint(element))
+			}
+		case nil:
+		default:
+			query.Add(name, fmt.Sprint(v))
+		}
+	}
+	return query, nil
+}
+
+// selectItems returns the elements of the array at the dot separated path of the
+// response. Without a path, the data array of JSON:API responses and top-level arrays
+// are used, and any other response is returned as a single item.
+func selectItems(body json.RawMessage, itemsPath string) ([]json.RawMessage, error) {
+	itemsPath = strings.TrimSuffix(strings.TrimSpace(itemsPath), "[]")
+	if itemsPath == "." {
+		return []json.RawMessage{body}, nil
+	}
+
+	value := body
+	if itemsPath == "" {
+		if data, ok := objectField(body, "data"); ok && isArray(data) {
+			value = data
+		}
+	} else {
+		for _, key := range strings.Split(itemsPath, ".") {
+			field, ok := objectField(value, key)
+			if !ok {
+				return nil, nil
+			}
+			value = field
+		}
+	}
+
+	if isNull(value) {
+		return nil, nil
+	}
+	if !isArray(value) {
+		return []json.RawMessage{value}, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(value, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// nextPageURL returns the URL of the page after the current one, or nil if it was the last.
+func nextPageURL(client *rawClient, current *url.URL, body json.RawMessage) (*url.URL, error) {
+	hints := apiRequestPageHints{}
+	if err := json.Unmarshal(body, &hints); err != nil {
+		// Responses which are not objects are not paginated
+		return nil, nil
+	}
+
+	var next *url.URL
+	switch {
+	case hints.Links.Next != nil && *hints.Links.Next != "":
+		resolved, err := client.resolve(*hints.Links.Next)
+		if err != nil {
+			return nil, err
+		}
+		next = resolved
+	case hints.Meta.Page.After != nil && *hints.Meta.Page.After != "":
+		next = withQueryParam(current, "page[cursor]", *hints.Meta.Page.After)
+	case hints.Meta.Pagination.hasNext():
+		next = withQueryParam(current, "page[number]", fmt.Sprint(*hints.Meta.Pagination.Number+1))
+	default:
+		return nil, nil
+	}
+
+	// Stop instead of looping forever on endpoints returning the same page again
+	if next.String() == current.String() {
+		return nil, nil
+	}
+	return next, nil
+}
+
+func withQueryParam(u *url.URL, name, value string) *url.URL {
+	next := *u
+	query := next.Query()
+	query.Set(name, value)
+	next.RawQuery = query.Encode()
+	return &next
+}
+
+func objectField(value json.RawMessage, key string) (json.RawMessage, bool) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(value, &object); err != nil {
+		return nil, false
+	}
+	field, ok := object[key]
+	return field, ok
+}
+
+func isArray(value json.RawMessage) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(value), []byte("["))
+}
+
+func isNull(value json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(value)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
@@ -0,0 +1,135 @@
+package datadog
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAPIRequestFollowsCursor(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/services", url.Values{"page[cursor]": nil}, fakeResponse{Body: `{"data": [{"id": "a"}, {"id": "b"}], "meta": {"page": {"after": "c1"}}}`})
+	f.handle("GET", "/api/v2/services", url.Values{"page[cursor]": {"c1"}}, fakeResponse{Body: `{"data": [{"id": "c"}], "meta": {"page": {}}}`})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_api_request",
+		Columns: []string{"path", "params", "item", "item_index", "page"},
+		Quals:   mergeQuals(stringQual("path", "=", "/api/v2/services"), jsonQual("params", `{"filter[env]": "prod", "include": ["a", "b"]}`)),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "item_index")
+	if got, want := columnValues(rows, "item"), []interface{}{
+		map[string]interface{}{"id": "a"},
+		map[string]interface{}{"id": "b"},
+		map[string]interface{}{"id": "c"},
+	}; !reflect.DeepEqual(got, want) {
+		t.Errorf("item = %v, want %v", got, want)
+	}
+	if got, want := columnValues(rows, "page"), []interface{}{int64(0), int64(0), int64(1)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("page = %v, want %v", got, want)
+	}
+	if want := map[string]interface{}{"filter[env]": "prod", "include": []interface{}{"a", "b"}}; !reflect.DeepEqual(rows[0]["params"], want) {
+		t.Errorf("params = %v, want %v", rows[0]["params"], want)
+	}
+	if rows[0]["path"] != "/api/v2/services" {
+		t.Errorf("path = %v", rows[0]["path"])
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/services")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	for _, req := range requests {
+		query := req.URL.Query()
+		if query.Get("filter[env]") != "prod" || !reflect.DeepEqual(query["include"], []string{"a", "b"}) {
+			t.Errorf("request %s lost the params", req.URL)
+		}
+	}
+}
+
+func TestAPIRequestItemsPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		itemsPath string
+		want      []interface{}
+	}{
+		{"top-level array", `[1, 2]`, "", []interface{}{float64(1), float64(2)}},
+		{"object", `{"valid": true}`, "", []interface{}{map[string]interface{}{"valid": true}}},
+		{"nested array", `{"data": {"attributes": {"slos": [{"id": "x"}]}}}`, "data.attributes.slos", []interface{}{map[string]interface{}{"id": "x"}}},
+		{"whole response", `{"data": [1]}`, ".", []interface{}{map[string]interface{}{"data": []interface{}{float64(1)}}}},
+		{"missing path", `{"data": [1]}`, "items", []interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeDatadog(t)
+			f.handle("GET", "/api/v1/things", nil, fakeResponse{Body: tt.body})
+
+			quals := stringQual("path", "=", "/api/v1/things")
+			if tt.itemsPath != "" {
+				quals = mergeQuals(quals, stringQual("items_path", "=", tt.itemsPath))
+			}
+			rows, err := f.query(testQuery{Table: "datadog_api_request", Columns: []string{"item", "item_index"}, Quals: quals})
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			rows = sortRows(rows, "item_index")
+			if got := columnValues(rows, "item"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("item = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIRequestFollowsPageNumbers(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/things", url.Values{"page[number]": nil}, fakeResponse{Body: `{"data": [1], "meta": {"pagination": {"number": 0, "last_number": 1}}}`})
+	f.handle("GET", "/api/v1/things", url.Values{"page[number]": {"1"}}, fakeResponse{Body: `{"data": [2], "meta": {"pagination": {"number": 1, "last_number": 1}}}`})
+
+	rows, err := f.query(testQuery{Table: "datadog_api_request", Columns: []string{"item"}, Quals: stringQual("path", "=", "/api/v1/things")})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Errorf("got %d rows, want 2", len(rows))
+	}
+}
+
+func TestAPIRequestRejectsOtherHosts(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/things", nil, fakeResponse{Body: `{"data": [1], "links": {"next": "https://example.com/api/v2/things?page=2"}}`})
+
+	_, err := f.query(testQuery{Table: "datadog_api_request", Columns: []string{"item"}, Quals: stringQual("path", "=", "/api/v2/things")})
+	if err == nil || !strings.Contains(err.Error(), "refusing to follow link") {
+		t.Errorf("error = %v, want the link to be refused", err)
+	}
+
+	_, err = f.query(testQuery{Table: "datadog_api_request", Columns: []string{"item"}, Quals: stringQual("path", "=", "https://example.com/api/v2/things")})
+	if err == nil || !strings.Contains(err.Error(), "must be an API path") {
+		t.Errorf("error = %v, want the path to be rejected", err)
+	}
+}
+
+func TestAPIRequestPathQueryString(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/monitor", nil, fakeResponse{Body: `[{"id": 1}]`})
+
+	if _, err := f.query(testQuery{
+		Table:   "datadog_api_request",
+		Columns: []string{"item"},
+		Quals:   mergeQuals(stringQual("path", "=", "/api/v1/monitor?page=1&name=a"), jsonQual("params", `{"name": "b"}`)),
+	}); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/monitor")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if want := (url.Values{"page": {"1"}, "name": {"b"}}); !reflect.DeepEqual(requests[0].URL.Query(), want) {
+		t.Errorf("query = %v, want %v", requests[0].URL.Query(), want)
+	}
+}
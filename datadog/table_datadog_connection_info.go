@@ -0,0 +1,53 @@
+package datadog
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableDatadogConnectionInfo(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "datadog_connection_info",
+		Description: "Information about the Datadog site, organization and keys of the connection.",
+		List: &plugin.ListConfig{
+			Hydrate: listConnectionInfo,
+		},
+		Columns: []*plugin.Column{
+			// Top columns
+			{Name: "site", Type: proto.ColumnType_STRING, Description: "The Datadog site of the connection, e.g. \"us1\" or \"eu1\". Set to the API host when a custom API URL is configured."},
+			{Name: "api_url", Type: proto.ColumnType_STRING, Transform: transform.FromField("APIURL"), Description: "The API URL used for all requests."},
+			{Name: "org_name", Type: proto.ColumnType_STRING, Description: "The name of the organization the keys belong to."},
+			{Name: "org_public_id", Type: proto.ColumnType_STRING, Transform: transform.FromField("OrgPublicID"), Description: "The public ID of the organization the keys belong to."},
+
+			// Other useful columns
+			{Name: "key_owner_id", Type: proto.ColumnType_STRING, Transform: transform.FromField("KeyOwnerID"), Description: "The ID of the user or service account owning the application key."},
+			{Name: "key_owner_handle", Type: proto.ColumnType_STRING, Description: "The handle of the user or service account owning the application key."},
+			{Name: "key_owner_email", Type: proto.ColumnType_STRING, Description: "The email of the user or service account owning the application key."},
+			{Name: "app_key_name", Type: proto.ColumnType_STRING, Description: "The name of the application key."},
+			{Name: "app_key_scoped", Type: proto.ColumnType_BOOL, Description: "Whether the application key is restricted to a set of authorization scopes."},
+
+			// JSON columns
+			{Name: "app_key_scopes", Type: proto.ColumnType_JSON, Description: "The authorization scopes the application key is restricted to."},
+			{Name: "api_versions", Type: proto.ColumnType_JSON, Transform: transform.FromField("APIVersions"), Description: "The Datadog API versions which responded successfully to the keys."},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("OrgName"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, resourceLink{Resource: "org", IDField: "OrgPublicID"}), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+		},
+	}
+}
+
+func listConnectionInfo(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	info, err := getConnectionInfo(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_connection_info.listConnectionInfo", "connection_error", err)
+		return nil, err
+	}
+
+	d.StreamListItem(ctx, info)
+
+	return nil, nil
+}
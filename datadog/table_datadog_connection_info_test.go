@@ -0,0 +1,137 @@
+package datadog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	connectionmanager "github.com/turbot/steampipe-plugin-sdk/v5/connection"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+func TestConnectionInfo(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/current_user/application_keys", nil, fakeResponse{Body: `{
+		"data": [{
+			"id": "key-1",
+			"attributes": {"name": "steampipe", "last4": "-key", "scopes": ["monitors_read", "dashboards_read"]},
+			"relationships": {"owned_by": {"data": {"id": "user-1"}}}
+		}]
+	}`})
+	f.handle("GET", "/api/v2/users/user-1", nil, fakeResponse{Body: `{
+		"data": {"type": "users", "id": "user-1", "attributes": {"handle": "jane@example.com", "email": "jane@example.com"}}
+	}`})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_connection_info",
+		Columns: []string{"org_name", "org_public_id", "app_key_name", "app_key_scoped", "app_key_scopes", "key_owner_handle", "api_versions"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	row := rows[0]
+	if row["org_name"] != "Test Org" || row["org_public_id"] != "abc123def" {
+		t.Errorf("got org %v/%v", row["org_name"], row["org_public_id"])
+	}
+	if row["app_key_name"] != "steampipe" || row["app_key_scoped"] != true {
+		t.Errorf("got app key %v, scoped %v", row["app_key_name"], row["app_key_scoped"])
+	}
+	if row["key_owner_handle"] != "jane@example.com" {
+		t.Errorf("key_owner_handle = %v", row["key_owner_handle"])
+	}
+}
+
+func TestConnectionInfoRejectedKey(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/validate", nil, fakeResponse{Status: 403, Body: `{"errors": ["Forbidden"]}`})
+
+	_, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}})
+	if err == nil {
+		t.Fatal("query succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "api_key was rejected") {
+		t.Errorf("error %q does not say the api_key was rejected", err)
+	}
+	if got := len(f.recorder.requestsTo("/api/v1/monitor")); got != 0 {
+		t.Errorf("got %d monitor requests, want none after the keys were rejected", got)
+	}
+}
+
+func TestConnectionInvalidSite(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.apiURLFromEnv = true
+	f.config = `site = "us2"`
+
+	_, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}})
+	if err == nil {
+		t.Fatal("query succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), `invalid site "us2"`) {
+		t.Errorf("error %q does not name the invalid site", err)
+	}
+	if got := len(f.recorder.requestsTo("/api/v1/validate")); got != 0 {
+		t.Errorf("got %d validate requests, want none for an invalid config", got)
+	}
+}
+
+func TestConnectionInfoCachesFailures(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/validate", nil, fakeResponse{Status: 403, Body: `{"errors": ["Forbidden"]}`})
+
+	cache, err := connectionmanager.NewConnectionCache("datadog_cache_test", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, appKey, apiURL, proxyURL := "test-api-key", "test-app-key", f.server.URL+"/", f.proxy.URL
+	connection := &plugin.Connection{Name: "datadog_cache_test", Config: datadogConfig{APIKey: &apiKey, AppKey: &appKey, ApiURL: &apiURL, ProxyURL: &proxyURL}}
+	ctx := context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+
+	for i := 0; i < 3; i++ {
+		if _, err := getConnectionInfo(ctx, connection, cache); err == nil || !strings.Contains(err.Error(), "api_key was rejected") {
+			t.Errorf("call %d: error = %v, want the api_key to be rejected", i, err)
+		}
+	}
+	if got := len(f.recorder.requestsTo("/api/v1/validate")); got != 1 {
+		t.Errorf("got %d validate requests, want the failure to be cached after 1", got)
+	}
+}
+
+func TestConnectionInfoAmbiguousAppKey(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/current_user/application_keys", nil, fakeResponse{Body: `{
+		"data": [
+			{"id": "key-1", "attributes": {"name": "steampipe", "last4": "-key"}, "relationships": {"owned_by": {"data": {"id": "user-1"}}}},
+			{"id": "key-2", "attributes": {"name": "other", "last4": "-key"}, "relationships": {"owned_by": {"data": {"id": "user-2"}}}}
+		]
+	}`})
+
+	rows, err := f.query(testQuery{Table: "datadog_connection_info", Columns: []string{"org_name", "app_key_name", "key_owner_handle"}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	if rows[0]["app_key_name"] != nil || rows[0]["key_owner_handle"] != nil {
+		t.Errorf("got app key %v owned by %v, want none for an ambiguous key", rows[0]["app_key_name"], rows[0]["key_owner_handle"])
+	}
+	if got := len(f.recorder.requestsTo("/api/v2/users/user-1")) + len(f.recorder.requestsTo("/api/v2/users/user-2")); got != 0 {
+		t.Errorf("got %d key owner requests, want none", got)
+	}
+}
+
+func TestConnectionInfoRejectedAppKey(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/org", nil, fakeResponse{Status: 403, Body: `{"errors": ["Forbidden"]}`})
+
+	_, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}})
+	if err == nil || !strings.Contains(err.Error(), "app_key was rejected") {
+		t.Errorf("error = %v, want the app_key to be rejected", err)
+	}
+}
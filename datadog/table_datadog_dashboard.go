@@ -16,12 +16,27 @@ func tableDatadogDashboard(ctx context.Context) *plugin.Table {
 		Description: "A dashboard is Datadog’s tool for visually tracking, analyzing, and displaying key performance metrics.",
 		Get: &plugin.GetConfig{
 			Hydrate:    getDashboard,
+			Tags:       apiFamilyTags("dashboards"),
 			KeyColumns: plugin.SingleColumn("id"),
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundError,
+			},
 		},
 		List: &plugin.ListConfig{
 			Hydrate: listDashboards,
+			Tags:    apiFamilyTags("dashboards"),
 		},
-		Columns: []*plugin.Column{
+		HydrateConfig: []plugin.HydrateConfig{
+			{
+				// The item may have been deleted since it was listed
+				Func: getDashboard,
+				Tags: apiFamilyTags("dashboards"),
+				IgnoreConfig: &plugin.IgnoreConfig{
+					ShouldIgnoreErrorFunc: isNotFoundError,
+				},
+			},
+		},
+		Columns: commonColumns([]*plugin.Column{
 			// Top columns
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "Dashboard identifier."},
 			{Name: "author_handle", Type: proto.ColumnType_STRING, Description: "Identifier of the dashboard author."},
@@ -41,7 +56,10 @@ func tableDatadogDashboard(ctx context.Context) *plugin.Table {
 			{Name: "template_variable_presets", Type: proto.ColumnType_JSON, Hydrate: getDashboard, Description: "List of template variables saved views."},
 			{Name: "template_variables", Type: proto.ColumnType_JSON, Hydrate: getDashboard, Description: "List of template variables for this dashboard."},
 			{Name: "widgets", Type: proto.ColumnType_JSON, Hydrate: getDashboard, Description: "List of widgets to display on the dashboard."},
-		},
+
+			// Steampipe standard columns
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, resourceLink{Resource: "dashboard", IDField: "Id"}), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+		}),
 	}
 }
 
@@ -53,10 +71,10 @@ func listDashboards(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateD
 	}
 
 	// https://github.com/DataDog/datadog-api-client-go/blob/master/api/v1/datadog/docs/DashboardsApi.md#listdashboards
-	resp, _, err := apiClient.DashboardsApi.ListDashboards(ctx, datadog.ListDashboardsOptionalParameters{})
+	resp, httpResp, err := apiClient.DashboardsApi.ListDashboards(ctx, datadog.ListDashboardsOptionalParameters{})
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_dashboard.listDashboards", "query_error", err)
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	for _, dashboard := range resp.GetDashboards() {
@@ -88,13 +106,10 @@ func getDashboard(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateDat
 		return nil, err
 	}
 
-	resp, _, err := apiClient.DashboardsApi.GetDashboard(ctx, dashboardID)
+	resp, httpResp, err := apiClient.DashboardsApi.GetDashboard(ctx, dashboardID)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_dashboard.getDashboard", "query_error", err)
-		if err.Error() == "404 Not Found" {
-			return nil, nil
-		}
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	return resp, nil
@@ -0,0 +1,65 @@
+package datadog
+
+import (
+	"testing"
+)
+
+func TestListDashboardsHydratesDetails(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/dashboard", nil, fakeResponse{Fixture: "v1/dashboards.json"})
+	f.handle("GET", "/api/v1/dashboard/abc-def-ghi", nil, fakeResponse{Fixture: "v1/dashboard.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_dashboard",
+		Columns: []string{"id", "title", "is_read_only", "reflow_type", "widgets", "template_variables"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	row := rows[0]
+	if row["title"] != "Web Overview" {
+		t.Errorf("title = %v", row["title"])
+	}
+	if row["reflow_type"] != "auto" {
+		t.Errorf("reflow_type = %v, want auto", row["reflow_type"])
+	}
+	if widgets, ok := row["widgets"].([]interface{}); !ok || len(widgets) != 1 {
+		t.Errorf("widgets = %v", row["widgets"])
+	}
+	if variables, ok := row["template_variables"].([]interface{}); !ok || len(variables) != 1 {
+		t.Errorf("template_variables = %v", row["template_variables"])
+	}
+}
+
+func TestListDashboardsSkipsDetailsWhenNotSelected(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/dashboard", nil, fakeResponse{Fixture: "v1/dashboards.json"})
+
+	if _, err := f.query(testQuery{Table: "datadog_dashboard", Columns: []string{"id", "title"}}); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := len(f.recorder.requestsTo("/api/v1/dashboard/abc-def-ghi")); got != 0 {
+		t.Errorf("got %d dashboard requests, want 0", got)
+	}
+}
+
+func TestGetDashboardNotFound(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/dashboard/missing", nil, fakeResponse{Status: 404, Body: `{"errors": ["Dashboard missing not found"]}`})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_dashboard",
+		Columns: []string{"id", "title"},
+		Quals:   stringQual("id", "=", "missing"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want 0", len(rows))
+	}
+}
@@ -10,17 +10,21 @@ import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// hostLink builds the akas and app_url of the host rows.
+var hostLink = resourceLink{Resource: "host", IDField: "Id", AppPath: "/infrastructure?host=%s", AppField: "HostName"}
+
 func tableDatadogHost(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_host",
 		Description: "A host is any piece of infrastructure that runs an instance of the Datadog Agent such as a bare metal instance or a VM.",
 		List: &plugin.ListConfig{
 			Hydrate: listHosts,
+			Tags:    apiFamilyTags("hosts"),
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "name", Require: plugin.Optional},
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns(append([]*plugin.Column{
 			// Top columns
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the host."},
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "ID of the host."},
@@ -39,7 +43,12 @@ func tableDatadogHost(ctx context.Context) *plugin.Table {
 			{Name: "metrics", Type: proto.ColumnType_JSON, Description: "An object containing host metrics such as CPU, iowait and load."},
 			{Name: "sources", Type: proto.ColumnType_JSON, Description: "An array containing the sources of the host metrics."},
 			{Name: "tags_by_source", Type: proto.ColumnType_JSON, Description: "An object containing tags for each data source such as AWS, Datadog Agent etc."},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("HostName"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, hostLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, hostLink), Description: "Link to the host in the Datadog app of the site."},
+		}, tagColumns("TagsBySource", "team", "env", "service")...)),
 	}
 }
 
@@ -72,10 +81,10 @@ func listHosts(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData)
 	count := int64(0)
 
 	for {
-		resp, _, err := apiClient.HostsApi.ListHosts(ctx, opts)
+		resp, httpResp, err := apiClient.HostsApi.ListHosts(ctx, opts)
 		if err != nil {
 			plugin.Logger(ctx).Error("datadog_host.listHosts", "query_error", err)
-			return nil, err
+			return nil, wrapAPIError(d, httpResp, err)
 		}
 
 		for _, host := range *resp.HostList {
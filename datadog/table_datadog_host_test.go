@@ -0,0 +1,74 @@
+package datadog
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListHostsPagination(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/hosts", url.Values{"start": nil}, fakeResponse{Fixture: "v1/hosts.json"})
+	f.handle("GET", "/api/v1/hosts", url.Values{"start": {"2"}}, fakeResponse{Body: `{"host_list": [], "total_matching": 2, "total_returned": 0}`})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_host",
+		Columns: []string{"id", "name", "up", "is_muted", "last_reported_time", "apps", "tags_by_source"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "name")
+
+	if got, want := columnValues(rows, "name"), []interface{}{"web-1", "web-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("name = %v, want %v", got, want)
+	}
+	if want := time.Unix(1650000000, 0).UTC(); rows[0]["last_reported_time"] != want {
+		t.Errorf("last_reported_time = %v, want %v", rows[0]["last_reported_time"], want)
+	}
+	if want := []interface{}{"agent", "nginx"}; !reflect.DeepEqual(rows[0]["apps"], want) {
+		t.Errorf("apps = %v, want %v", rows[0]["apps"], want)
+	}
+	if rows[1]["is_muted"] != true {
+		t.Errorf("is_muted = %v, want true", rows[1]["is_muted"])
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/hosts")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := requests[0].URL.Query().Get("count"); got != "1000" {
+		t.Errorf("count = %q, want 1000", got)
+	}
+}
+
+func TestListHostsNameQualAndLimit(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/hosts", nil, fakeResponse{Fixture: "v1/hosts.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_host",
+		Columns: []string{"id", "name"},
+		Quals:   stringQual("name", "=", "web-1"),
+		Limit:   1,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("got %d rows, want 1", len(rows))
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/hosts")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	query := requests[0].URL.Query()
+	if got := query.Get("filter"); got != "exact:host:web-1" {
+		t.Errorf("filter = %q, want exact:host:web-1", got)
+	}
+	if got := query.Get("count"); got != "1" {
+		t.Errorf("count = %q, want the limit pushed down", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	datadog "github.com/DataDog/datadog-api-client-go/api/v1/datadog"
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
 func tableDatadogIntegrationAws(ctx context.Context) *plugin.Table {
@@ -14,13 +15,14 @@ func tableDatadogIntegrationAws(ctx context.Context) *plugin.Table {
 		Description: "Datadog AWS integration resource.",
 		List: &plugin.ListConfig{
 			Hydrate: listAWSIntegrations,
+			Tags:    apiFamilyTags("integrations"),
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "account_id", Require: plugin.Optional},
 				{Name: "role_name", Require: plugin.Optional},
 				{Name: "access_key_id", Require: plugin.Optional},
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns(append([]*plugin.Column{
 			// Top columns
 			{Name: "account_id", Type: proto.ColumnType_STRING, Description: "Your AWS Account ID without dashes."},
 			{Name: "role_name", Type: proto.ColumnType_STRING, Description: "Your Datadog role delegation name."},
@@ -37,7 +39,12 @@ func tableDatadogIntegrationAws(ctx context.Context) *plugin.Table {
 			{Name: "excluded_regions", Type: proto.ColumnType_JSON, Description: "An array of AWS regions to exclude from metrics collection."},
 			{Name: "filter_tags", Type: proto.ColumnType_JSON, Description: "List of tags (in the form 'key:value') that define a filter which is used when collecting EC2 or Lambda resources. These key:value pairs can be used to both whitelist and blacklist tags."},
 			{Name: "host_tags", Type: proto.ColumnType_JSON, Description: "Array of tags (in the form `key:value`) to add to all hosts and metrics reporting through this integration."},
-		},
+			{Name: "filter_tags_map", Type: proto.ColumnType_JSON, Transform: transform.FromField("FilterTags").Transform(tagsToMap), Description: "The filter tags as an object of tag keys to the list of their values."},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("AccountId"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, resourceLink{Resource: "integration_aws", IDField: "AccountId"}), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+		}, tagColumns("HostTags", "team", "env", "service")...)),
 	}
 }
 
@@ -61,10 +68,10 @@ func listAWSIntegrations(ctx context.Context, d *plugin.QueryData, _ *plugin.Hyd
 	}
 
 	// Paging not supported by this API as of Date 10-25-2021
-	resp, _, err := apiClient.AWSIntegrationApi.ListAWSAccounts(ctx, opts)
+	resp, httpResp, err := apiClient.AWSIntegrationApi.ListAWSAccounts(ctx, opts)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_integration_aws.listAWSIntegrations", "query_error", err)
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	for _, account := range resp.GetAccounts() {
@@ -0,0 +1,42 @@
+package datadog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListAWSIntegrations(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/integration/aws", nil, fakeResponse{Fixture: "v1/aws_accounts.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_integration_aws",
+		Columns: []string{"account_id", "role_name", "metrics_collection_enabled", "excluded_regions", "account_specific_namespace_rules"},
+		Quals:   stringQual("account_id", "=", "123456789012"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	row := rows[0]
+	if row["role_name"] != "DatadogIntegrationRole" || row["metrics_collection_enabled"] != true {
+		t.Errorf("got row %v", row)
+	}
+	if want := []interface{}{"ap-east-1"}; !reflect.DeepEqual(row["excluded_regions"], want) {
+		t.Errorf("excluded_regions = %v, want %v", row["excluded_regions"], want)
+	}
+	if want := map[string]interface{}{"opsworks": false}; !reflect.DeepEqual(row["account_specific_namespace_rules"], want) {
+		t.Errorf("account_specific_namespace_rules = %v, want %v", row["account_specific_namespace_rules"], want)
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/integration/aws")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if got := requests[0].URL.Query().Get("account_id"); got != "123456789012" {
+		t.Errorf("account_id = %q, want it pushed down", got)
+	}
+}
@@ -0,0 +1,329 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	datadog "github.com/DataDog/datadog-api-client-go/api/v2/datadog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// defaultLogAggregateWindow is the time range aggregated when the query has no
+// timestamp quals, which is also the default of the API.
+const defaultLogAggregateWindow = 15 * time.Minute
+
+func tableDatadogLogAggregate(ctx context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "datadog_log_aggregate",
+		Description: "Aggregate Datadog log events into buckets, e.g. counts of error logs per service.",
+		List: &plugin.ListConfig{
+			Hydrate: listLogAggregate,
+			Tags:    apiFamilyTags("logs_search"),
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "query", Require: plugin.Optional},
+				{Name: "timestamp", Operators: []string{">", ">=", "<", "<="}, Require: plugin.Optional},
+				{Name: "group_by", Require: plugin.Optional},
+				{Name: "group_limit", Require: plugin.Optional},
+				{Name: "compute", Require: plugin.Optional},
+				{Name: "interval", Require: plugin.Optional},
+			},
+		},
+		Columns: commonColumns([]*plugin.Column{
+			// Top columns
+			{Name: "timestamp", Type: proto.ColumnType_TIMESTAMP, Description: "The start of the bucket when an interval is set, otherwise the start of the aggregated time range. Defaults to the last 15 minutes."},
+			{Name: "query", Type: proto.ColumnType_STRING, Transform: transform.FromQual("query"), Description: "Query selecting the logs to aggregate. Refer https://docs.datadoghq.com/logs/explorer/search_syntax"},
+			{Name: "count", Type: proto.ColumnType_INT, Description: "The number of logs in the bucket, if count is one of the computes."},
+			{Name: "value", Type: proto.ColumnType_DOUBLE, Description: "The value of the first compute of the bucket."},
+
+			// Other useful columns
+			{Name: "interval", Type: proto.ColumnType_STRING, Transform: transform.FromQual("interval"), Description: "The size of the time buckets, e.g. 1h. Without an interval, the whole time range is aggregated."},
+			{Name: "group_limit", Type: proto.ColumnType_INT, Transform: transform.FromQual("group_limit"), Description: "The maximum number of values of each group by facet, 10 by default."},
+
+			// JSON columns
+			{Name: "group_by", Type: proto.ColumnType_JSON, Transform: transform.FromQual("group_by"), Description: "The facets to group the logs by, e.g. [\"service\", \"@http.status_code\"]."},
+			{Name: "compute", Type: proto.ColumnType_JSON, Transform: transform.FromQual("compute"), Description: "The values to compute per bucket, e.g. [\"count\", \"cardinality(@usr.id)\", \"avg(@duration)\", \"pc99(@duration)\"]. Defaults to [\"count\"]."},
+			{Name: "groups", Type: proto.ColumnType_JSON, Description: "The values of the group by facets of the bucket."},
+			{Name: "computes", Type: proto.ColumnType_JSON, Description: "The computed values of the bucket, keyed by compute."},
+		}),
+	}
+}
+
+// logAggregateRow is a bucket of the aggregated logs, or a time bucket of it when an
+// interval is set.
+type logAggregateRow struct {
+	Timestamp time.Time
+	Count     *int64
+	Value     *float64
+	Groups    map[string]interface{}
+	Computes  map[string]*float64
+}
+
+// logAggregateResponse is a page of the Logs Aggregate API. It is decoded here as the
+// generated client expects group values to be strings, while numeric facets return numbers.
+type logAggregateResponse struct {
+	Data struct {
+		Buckets []struct {
+			By       map[string]interface{}     `json:"by"`
+			Computes map[string]json.RawMessage `json:"computes"`
+		} `json:"buckets"`
+	} `json:"data"`
+	Meta struct {
+		Page struct {
+			After *string `json:"after"`
+		} `json:"page"`
+	} `json:"meta"`
+}
+
+// logAggregatePoint is a point of a compute with an interval.
+type logAggregatePoint struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+func listLogAggregate(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	computeExprs, err := logAggregateComputeQual(d)
+	if err != nil {
+		return nil, err
+	}
+	groupBy, err := logAggregateGroupByQual(d)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := connectRaw(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_log_aggregate.listLogAggregate", "connection_error", err)
+		return nil, err
+	}
+
+	from, to := logAggregateTimeRange(d)
+	query := d.EqualsQualString("query")
+	if query == "" {
+		query = "*"
+	}
+	interval := d.EqualsQualString("interval")
+
+	computes := make([]datadog.LogsCompute, 0, len(computeExprs))
+	for _, expr := range computeExprs {
+		compute, err := parseLogsCompute(expr)
+		if err != nil {
+			return nil, err
+		}
+		if interval != "" {
+			compute.SetType(datadog.LOGSCOMPUTETYPE_TIMESERIES)
+			compute.SetInterval(interval)
+		}
+		computes = append(computes, compute)
+	}
+
+	groups := make([]datadog.LogsGroupBy, 0, len(groupBy))
+	for _, facet := range groupBy {
+		group := datadog.NewLogsGroupBy(facet)
+		if d.EqualsQuals["group_limit"] != nil {
+			group.SetLimit(d.EqualsQuals["group_limit"].GetInt64Value())
+		}
+		groups = append(groups, *group)
+	}
+
+	body := datadog.NewLogsAggregateRequest()
+	body.SetCompute(computes)
+	body.SetFilter(datadog.LogsQueryFilter{
+		Query: &query,
+		From:  datadog.PtrString(from.Format(time.RFC3339Nano)),
+		To:    datadog.PtrString(to.Format(time.RFC3339Nano)),
+	})
+	if len(groups) > 0 {
+		body.SetGroupBy(groups)
+	}
+
+	for {
+		var resp logAggregateResponse
+		// https://docs.datadoghq.com/api/latest/logs/#aggregate-events
+		if err := client.do(ctx, "POST", "/api/v2/logs/analytics/aggregate", nil, body, &resp); err != nil {
+			plugin.Logger(ctx).Error("datadog_log_aggregate.listLogAggregate", "query_error", err)
+			return nil, wrapAPIError(d, nil, err)
+		}
+
+		for _, bucket := range resp.Data.Buckets {
+			rows, err := logAggregateRows(computeExprs, bucket.By, bucket.Computes, from)
+			if err != nil {
+				plugin.Logger(ctx).Error("datadog_log_aggregate.listLogAggregate", "parse_error", err)
+				return nil, err
+			}
+			for _, row := range rows {
+				d.StreamListItem(ctx, row)
+
+				// Check if context has been cancelled or if the limit has been hit (if specified)
+				if d.RowsRemaining(ctx) == 0 {
+					return nil, nil
+				}
+			}
+		}
+
+		if resp.Meta.Page.After == nil || *resp.Meta.Page.After == "" {
+			break
+		}
+		body.SetPage(datadog.LogsAggregateRequestPage{Cursor: resp.Meta.Page.After})
+	}
+
+	return nil, nil
+}
+
+// logAggregateTimeRange returns the time range of the timestamp quals, by default the
+// last 15 minutes. The start of an exclusive range is moved by a millisecond, the
+// resolution of the API, so the rows of totals, which have the start as timestamp,
+// pass the quals.
+func logAggregateTimeRange(d *plugin.QueryData) (time.Time, time.Time) {
+	to := time.Now().UTC().Truncate(time.Millisecond)
+	var from time.Time
+	if d.Quals["timestamp"] != nil {
+		for _, q := range d.Quals["timestamp"].Quals {
+			timestamp := q.Value.GetTimestampValue().AsTime()
+			switch q.Operator {
+			case ">":
+				from = timestamp.Add(time.Millisecond)
+			case ">=":
+				from = timestamp
+			case "<", "<=":
+				to = timestamp
+			}
+		}
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultLogAggregateWindow)
+	}
+	return from, to
+}
+
+// logAggregateComputeQual returns the computes of the compute qual, a JSON array of
+// computes or a single compute, by default a count.
+func logAggregateComputeQual(d *plugin.QueryData) ([]string, error) {
+	computes, err := stringListQual(d, "compute")
+	if err != nil {
+		return nil, err
+	}
+	if len(computes) == 0 {
+		return []string{"count"}, nil
+	}
+	return computes, nil
+}
+
+// logAggregateGroupByQual returns the facets of the group_by qual, a JSON array of
+// facets or a single facet.
+func logAggregateGroupByQual(d *plugin.QueryData) ([]string, error) {
+	return stringListQual(d, "group_by")
+}
+
+// stringListQual decodes the JSON qual of the column, which is either an array of
+// strings or a single string.
+func stringListQual(d *plugin.QueryData, column string) ([]string, error) {
+	qual := d.EqualsQuals[column]
+	if qual == nil {
+		return nil, nil
+	}
+	raw := qual.GetJsonbValue()
+	var values []string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		var value string
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, fmt.Errorf("%s must be a JSON array of strings, e.g. [\"service\"], got %s", column, raw)
+		}
+		values = []string{value}
+	}
+	return values, nil
+}
+
+// computeExpr matches computes like "count" or "avg(@duration)".
+var computeExpr = regexp.MustCompile(`(?i)^\s*([a-z0-9]+)\s*(?:\(\s*([^()\s]*)\s*\))?\s*$`)
+
+// parseLogsCompute parses a compute like "count", "cardinality(@usr.id)" or
+// "pc99(@duration)" into the aggregation function and the measure it applies to.
+func parseLogsCompute(expr string) (datadog.LogsCompute, error) {
+	match := computeExpr.FindStringSubmatch(expr)
+	if match == nil {
+		return datadog.LogsCompute{}, fmt.Errorf("invalid compute %q, must be an aggregation optionally applied to a measure, e.g. count or avg(@duration)", expr)
+	}
+	aggregation, err := datadog.NewLogsAggregationFunctionFromValue(strings.ToLower(match[1]))
+	if err != nil {
+		return datadog.LogsCompute{}, fmt.Errorf("invalid compute %q, the aggregation must be one of: count, cardinality, pc75, pc90, pc95, pc98, pc99, sum, min, max, avg", expr)
+	}
+	compute := datadog.NewLogsCompute(*aggregation)
+	if match[2] != "" {
+		compute.SetMetric(match[2])
+	} else if *aggregation != datadog.LOGSAGGREGATIONFUNCTION_COUNT {
+		return datadog.LogsCompute{}, fmt.Errorf("invalid compute %q, %s must be applied to a measure, e.g. %s(@duration)", expr, match[1], match[1])
+	}
+	return *compute, nil
+}
+
+// logAggregateRows builds the rows of a bucket. The values of the computes are keyed by
+// their position, c0 for the first compute. Computes with an interval return a series
+// of points, which are merged into a row per point in time.
+func logAggregateRows(computeExprs []string, groups map[string]interface{}, values map[string]json.RawMessage, from time.Time) ([]*logAggregateRow, error) {
+	rowsByTime := map[time.Time]*logAggregateRow{}
+	rowAt := func(timestamp time.Time) *logAggregateRow {
+		// Buckets are aligned to the interval, so the first may start before the range
+		if timestamp.Before(from) {
+			timestamp = from
+		}
+		row, ok := rowsByTime[timestamp]
+		if !ok {
+			row = &logAggregateRow{Timestamp: timestamp, Groups: groups, Computes: map[string]*float64{}}
+			rowsByTime[timestamp] = row
+		}
+		return row
+	}
+
+	for i, expr := range computeExprs {
+		raw, ok := values[fmt.Sprintf("c%d", i)]
+		if !ok || isNull(raw) {
+			continue
+		}
+		if isArray(raw) {
+			var points []logAggregatePoint
+			if err := json.Unmarshal(raw, &points); err != nil {
+				return nil, err
+			}
+			for _, point := range points {
+				setLogAggregateValue(rowAt(point.Time), i, expr, point.Value)
+			}
+			continue
+		}
+		var value float64
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		setLogAggregateValue(rowAt(from), i, expr, &value)
+	}
+
+	// A bucket without computed values, e.g. of an empty series, still has its groups
+	if len(rowsByTime) == 0 {
+		rowAt(from)
+	}
+
+	rows := make([]*logAggregateRow, 0, len(rowsByTime))
+	for _, row := range rowsByTime {
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Timestamp.Before(rows[j].Timestamp) })
+	return rows, nil
+}
+
+func setLogAggregateValue(row *logAggregateRow, index int, expr string, value *float64) {
+	row.Computes[expr] = value
+	if index == 0 {
+		row.Value = value
+	}
+	if value != nil && strings.TrimSpace(strings.ToLower(expr)) == "count" {
+		count := int64(math.Round(*value))
+		row.Count = &count
+	}
+}
@@ -0,0 +1,143 @@
+package datadog
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListLogAggregateGroupBy(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("POST", "/api/v2/logs/analytics/aggregate", nil,
+		fakeResponse{Body: `{"data": {"buckets": [
+			{"by": {"service": "web", "@http.status_code": 500}, "computes": {"c0": 42, "c1": 0.25}},
+			{"by": {"service": "api", "@http.status_code": 500}, "computes": {"c0": 7, "c1": 1.5}}
+		]}, "meta": {"page": {"after": "next"}, "status": "done"}}`},
+		fakeResponse{Body: `{"data": {"buckets": [
+			{"by": {"service": "worker", "@http.status_code": 502}, "computes": {"c0": 1, "c1": 3}}
+		]}, "meta": {"status": "done"}}`},
+	)
+
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := f.query(testQuery{
+		Table:   "datadog_log_aggregate",
+		Columns: []string{"timestamp", "query", "groups", "count", "value", "computes"},
+		Quals: mergeQuals(
+			stringQual("query", "=", "status:error"),
+			timestampQual("timestamp", ">=", from),
+			jsonQual("group_by", `["service", "@http.status_code"]`),
+			jsonQual("compute", `["count", "avg(@duration)"]`),
+		),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/analytics/aggregate")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(requests[0].Body, &body); err != nil {
+		t.Fatalf("invalid request body %s: %v", requests[0].Body, err)
+	}
+	filter := body["filter"].(map[string]interface{})
+	if filter["query"] != "status:error" || filter["from"] != "2024-05-01T00:00:00Z" {
+		t.Errorf("filter = %v", filter)
+	}
+	wantCompute := []interface{}{
+		map[string]interface{}{"aggregation": "count", "type": "total"},
+		map[string]interface{}{"aggregation": "avg", "metric": "@duration", "type": "total"},
+	}
+	if !reflect.DeepEqual(body["compute"], wantCompute) {
+		t.Errorf("compute = %v, want %v", body["compute"], wantCompute)
+	}
+	if groupBy := body["group_by"].([]interface{}); len(groupBy) != 2 || groupBy[1].(map[string]interface{})["facet"] != "@http.status_code" {
+		t.Errorf("group_by = %v", body["group_by"])
+	}
+	if !strings.Contains(string(requests[1].Body), `"cursor":"next"`) {
+		t.Errorf("second request body = %s, want the cursor of the first page", requests[1].Body)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	var web map[string]interface{}
+	for _, row := range rows {
+		if row["groups"].(map[string]interface{})["service"] == "web" {
+			web = row
+		}
+	}
+	if web == nil {
+		t.Fatalf("no row for the web service in %v", rows)
+	}
+	if web["count"] != int64(42) || web["value"] != float64(42) {
+		t.Errorf("count, value = %v, %v, want 42, 42", web["count"], web["value"])
+	}
+	if want := map[string]interface{}{"count": float64(42), "avg(@duration)": 0.25}; !reflect.DeepEqual(web["computes"], want) {
+		t.Errorf("computes = %v, want %v", web["computes"], want)
+	}
+	if web["groups"].(map[string]interface{})["@http.status_code"] != float64(500) {
+		t.Errorf("groups = %v", web["groups"])
+	}
+	if web["timestamp"] != from || web["query"] != "status:error" {
+		t.Errorf("timestamp, query = %v, %v", web["timestamp"], web["query"])
+	}
+}
+
+func TestListLogAggregateInterval(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("POST", "/api/v2/logs/analytics/aggregate", nil,
+		fakeResponse{Body: `{"data": {"buckets": [
+			{"by": {}, "computes": {"c0": [
+				{"time": "2024-05-01T00:00:00.000Z", "value": 10},
+				{"time": "2024-05-01T01:00:00.000Z", "value": 20}
+			]}}
+		]}, "meta": {"status": "done"}}`},
+	)
+
+	from := time.Date(2024, 5, 1, 0, 30, 0, 0, time.UTC)
+	rows, err := f.query(testQuery{
+		Table:   "datadog_log_aggregate",
+		Columns: []string{"timestamp", "count", "interval"},
+		Quals: mergeQuals(
+			timestampQual("timestamp", ">=", from),
+			stringQual("interval", "=", "1h"),
+		),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/analytics/aggregate")
+	if len(requests) != 1 || !strings.Contains(string(requests[0].Body), `"type":"timeseries"`) || !strings.Contains(string(requests[0].Body), `"interval":"1h"`) {
+		t.Fatalf("requests = %v, want a timeseries compute with the interval", requests)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	byTime := map[time.Time]interface{}{}
+	for _, row := range rows {
+		byTime[row["timestamp"].(time.Time)] = row["count"]
+	}
+	// The first bucket starts before the time range, so it is reported at its start
+	want := map[time.Time]interface{}{from: int64(10), time.Date(2024, 5, 1, 1, 0, 0, 0, time.UTC): int64(20)}
+	if !reflect.DeepEqual(byTime, want) {
+		t.Errorf("counts = %v, want %v", byTime, want)
+	}
+}
+
+func TestParseLogsCompute(t *testing.T) {
+	compute, err := parseLogsCompute("PC99(@Duration)")
+	if err != nil || compute.Aggregation != "pc99" || compute.GetMetric() != "@Duration" {
+		t.Errorf("parseLogsCompute = %+v, %v", compute, err)
+	}
+	for _, expr := range []string{"median(@duration)", "avg", "count(", ""} {
+		if _, err := parseLogsCompute(expr); err == nil {
+			t.Errorf("parseLogsCompute(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -2,26 +2,43 @@ package datadog
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	datadog "github.com/DataDog/datadog-api-client-go/api/v2/datadog"
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/quals"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// logEventLink builds the akas and app_url of the log event rows.
+var logEventLink = resourceLink{Resource: "log_event", IDField: "Id", AppPath: "/logs?event=%s"}
+
 func tableDatadogLogEvent(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_log_event",
 		Description: "Datadog log events are records of notable changes in your environments.",
 		List: &plugin.ListConfig{
 			Hydrate: listLogEvent,
+			Tags:    apiFamilyTags("logs_search"),
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "query", Require: plugin.Optional},
 				{Name: "timestamp", Operators: []string{">", ">=", "=", "<", "<="}, Require: plugin.Optional},
+				{Name: "index", Require: plugin.Optional},
+				{Name: "storage_tier", Require: plugin.Optional},
+				{Name: "time_zone", Require: plugin.Optional},
+				{Name: "service", Operators: []string{"=", "<>"}, Require: plugin.Optional},
+				{Name: "host", Operators: []string{"=", "<>"}, Require: plugin.Optional},
+				{Name: "status", Operators: []string{"=", "<>"}, Require: plugin.Optional},
+				{Name: "trace_id", Operators: []string{"=", "<>"}, Require: plugin.Optional},
+				{Name: "env", Operators: []string{"=", "<>"}, Require: plugin.Optional},
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns(append([]*plugin.Column{
 			// Top columns
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "Unique ID of the Log."},
 			{Name: "timestamp", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("Attributes.Timestamp"), Description: "Timestamp of log."},
@@ -32,83 +49,472 @@ func tableDatadogLogEvent(ctx context.Context) *plugin.Table {
 			// Other useful columns
 			{Name: "host", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Host"), Description: "Name of the machine from where the logs are being sent."},
 			{Name: "message", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Message"), Description: "The message of the log."},
+			{Name: "index", Type: proto.ColumnType_STRING, Description: "The index the log was found in. Only set when the index is specified in the where clause, as the API does not return it."},
+			{Name: "storage_tier", Type: proto.ColumnType_STRING, Description: "The storage tier the log was found in: indexes (default), online-archives or flex."},
+			{Name: "time_zone", Type: proto.ColumnType_STRING, Transform: transform.FromQual("time_zone"), Description: "The time zone of the search, e.g. UTC+1 or Europe/Paris, which applies to dates without time zone in the query."},
+			{Name: "trace_id", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes").TransformP(logAttribute, logTraceID), Description: "The ID of the APM trace the log belongs to."},
+			{Name: "span_id", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes").TransformP(logAttribute, logSpanID), Description: "The ID of the APM span the log belongs to."},
+			{Name: "env", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes").TransformP(logAttribute, logEnv), Description: "The environment of the service, from the env attribute or else the env tag."},
+			{Name: "version", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes").TransformP(logAttribute, logVersion), Description: "The version of the service, from the version attribute or else the version tag."},
+			{Name: "source", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes").TransformP(logAttribute, logSource), Description: "The integration the log came from, e.g. nginx, from the source attribute or else the source tag."},
+			{Name: "http_status_code", Type: proto.ColumnType_INT, Transform: transform.FromField("Attributes").TransformP(logAttribute, logHTTPStatusCode).Transform(logAttributeInt), Description: "The HTTP status code of the request, from the http.status_code attribute."},
+			{Name: "error_kind", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes").TransformP(logAttribute, logErrorKind), Description: "The type or class of the error, from the error.kind attribute."},
 
 			// JSON columns
 			{Name: "attributes", Type: proto.ColumnType_JSON, Transform: transform.FromField("Attributes.Attributes"), Description: "JSON object of attributes for log."},
 			{Name: "tags", Type: proto.ColumnType_JSON, Transform: transform.FromField("Attributes.Tags"), Description: "Array of tags associated with log."},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromValue().Transform(logEventTitle), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, logEventLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, logEventLink), Description: "Link to the log in the Datadog app of the site."},
+		}, tagColumns("Attributes.Tags", "team")...)),
 	}
 }
 
+// logEvent is a log event and where it was searched, as the search response does not
+// tell the index of the events.
+type logEvent struct {
+	datadog.Log
+	// The index searched for the event, nil when all indexes were searched
+	Index *string
+	// The storage tier searched for the event
+	StorageTier string
+}
+
+// logsSearchRequest is the body of the log search endpoint. It is built here as the
+// filter of the generated client lacks the storage tier.
+type logsSearchRequest struct {
+	Filter  logsSearchFilter   `json:"filter"`
+	Options *logsSearchOptions `json:"options,omitempty"`
+	Page    logsSearchPage     `json:"page"`
+	Sort    string             `json:"sort"`
+}
+
+type logsSearchFilter struct {
+	Query       string   `json:"query,omitempty"`
+	From        string   `json:"from,omitempty"`
+	To          string   `json:"to,omitempty"`
+	Indexes     []string `json:"indexes,omitempty"`
+	StorageTier string   `json:"storage_tier,omitempty"`
+}
+
+type logsSearchOptions struct {
+	Timezone string `json:"timezone,omitempty"`
+}
+
+type logsSearchPage struct {
+	Cursor *string `json:"cursor,omitempty"`
+	Limit  int64   `json:"limit"`
+}
+
+// logsSearchResponse is a page of the log search endpoint.
+type logsSearchResponse struct {
+	Data []datadog.Log `json:"data"`
+	Meta struct {
+		Page struct {
+			After *string `json:"after"`
+		} `json:"page"`
+	} `json:"meta"`
+}
+
+// defaultLogStorageTier is the storage tier searched when the query has no storage_tier qual.
+const defaultLogStorageTier = "indexes"
+
 func listLogEvent(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
-	ctx, apiClient, _, err := connectV2(ctx, d)
+	client, err := connectRaw(ctx, d)
 	if err != nil {
-		plugin.Logger(ctx).Error("datadog_log_event.listLogEvents", "connection_error", err)
+		plugin.Logger(ctx).Error("datadog_log_event.listLogEvent", "connection_error", err)
 		return nil, err
 	}
 
-	sort := datadog.LogsSort("timestamp")
-	opts := *datadog.NewListLogsGetOptionalParameters()
-	opts.WithSort(sort)
-	opts.WithPageLimit(100)
+	req := logsSearchRequest{
+		Sort: "timestamp",
+		Page: logsSearchPage{Limit: 100},
+	}
 
 	limit := d.QueryContext.Limit
-	if d.QueryContext.Limit != nil {
-		if *limit < int64(*opts.PageLimit) {
-			opts.WithPageLimit(int32(*limit))
-		}
+	if limit != nil && *limit < req.Page.Limit {
+		req.Page.Limit = *limit
 	}
 
 	// Search syntax - https://docs.datadoghq.com/logs/explorer/search_syntax/
-	query := d.EqualsQualString("query")
-	if query != "" {
-		opts.WithFilterQuery(query)
+	req.Filter.Query = logSearchQuery(d)
+
+	req.Filter.StorageTier = defaultLogStorageTier
+	if tier := d.EqualsQualString("storage_tier"); tier != "" {
+		req.Filter.StorageTier = tier
+	}
+	if timeZone := d.EqualsQualString("time_zone"); timeZone != "" {
+		req.Options = &logsSearchOptions{Timezone: timeZone}
 	}
 
 	// By default the API only returns logs for the last 15 minutes
+	var timeRange logTimeRange
 	quals := d.Quals
 	if quals["timestamp"] != nil {
-		opts.WithFilterTo(time.Now())
+		timeRange.to = time.Now()
 		for _, q := range quals["timestamp"].Quals {
 			timestamp := q.Value.GetTimestampValue().AsTime()
 			switch q.Operator {
 			case "=":
-				opts.WithFilterFrom(timestamp)
-				opts.WithFilterTo(timestamp)
+				timeRange.from = timestamp
+				timeRange.to = timestamp
 			case ">=", ">":
-				opts.WithFilterFrom(timestamp)
+				timeRange.from = timestamp
 			case "<", "<=":
-				opts.WithFilterTo(timestamp)
+				timeRange.to = timestamp
 			}
 		}
+		if !timeRange.from.IsZero() {
+			req.Filter.From = timeRange.from.Format(time.RFC3339Nano)
+		}
+		req.Filter.To = timeRange.to.Format(time.RFC3339Nano)
 	}
 
-	for {
-		// https://github.com/DataDog/datadog-api-client-go/blob/master/api/v2/datadog/docs/LogsApi.md#listlogsget
-		resp, _, err := apiClient.LogsApi.ListLogsGet(ctx, opts)
-		if err != nil {
-			plugin.Logger(ctx).Error("datadog_log_event.listLogEvents", "query_error", err)
+	// Search the indexes one at a time, so the rows tell which index they are from
+	indexes := qualStrings(d, "index")
+	if len(indexes) == 0 {
+		return nil, searchLogEvents(ctx, d, client, req, timeRange, nil)
+	}
+	for _, index := range indexes {
+		index := index
+		indexReq := req
+		indexReq.Filter.Indexes = []string{index}
+		if err := searchLogEvents(ctx, d, client, indexReq, timeRange, &index); err != nil {
 			return nil, err
 		}
+		if d.RowsRemaining(ctx) == 0 {
+			break
+		}
+	}
 
-		for _, log := range resp.GetData() {
-			d.StreamListItem(ctx, log)
-			// Check if context has been cancelled or if the limit has been hit (if specified)
-			if d.RowsRemaining(ctx) == 0 {
-				return nil, nil
+	return nil, nil
+}
+
+// searchLogEvents streams the log events of the search until the limit of the query.
+// The time range is split into log_slices slices, which are searched concurrently and
+// streamed one after another, so the rows keep the timestamp order of the search.
+func searchLogEvents(ctx context.Context, d *plugin.QueryData, client *rawClient, req logsSearchRequest, timeRange logTimeRange, index *string) error {
+	slices := logSlices(d, timeRange)
+	if len(slices) == 1 {
+		err := searchLogPages(ctx, d, client, req, func(logs []datadog.Log) bool {
+			return streamLogEvents(ctx, d, logs, req, index, nil, nil)
+		})
+		if err != nil {
+			return wrapAPIError(d, nil, err)
+		}
+		return nil
+	}
+
+	// Stop the searches of the slices once the limit is hit
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type slicePages struct {
+		// The pages of the slice, closed after the last page or an error
+		pages chan []datadog.Log
+		err   error
+	}
+
+	// Each slice buffers a page ahead of the streamed slice, and waits for it to be
+	// streamed before it requests the next page.
+	results := make([]*slicePages, len(slices))
+	for i, slice := range slices {
+		result := &slicePages{pages: make(chan []datadog.Log, 1)}
+		results[i] = result
+
+		sliceReq := req
+		sliceReq.Filter.From = slice.from.Format(time.RFC3339Nano)
+		sliceReq.Filter.To = slice.to.Format(time.RFC3339Nano)
+		go func() {
+			defer close(result.pages)
+			result.err = searchLogPages(ctx, d, client, sliceReq, func(logs []datadog.Log) bool {
+				select {
+				case result.pages <- logs:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+		}()
+	}
+
+	// Logs at the boundary of two slices may be returned by both, so the logs of a
+	// slice that were already streamed with the slice before it are skipped.
+	var streamed map[string]bool
+	for i, result := range results {
+		skip := streamed
+		streamed = map[string]bool{}
+		var boundary *time.Time
+		if i < len(slices)-1 {
+			boundary = &slices[i+1].from
+		}
+		for logs := range result.pages {
+			if !streamLogEvents(ctx, d, logs, req, index, skip, func(log datadog.Log) {
+				attributes := log.GetAttributes()
+				if boundary != nil && !attributes.GetTimestamp().Before(*boundary) {
+					streamed[log.GetId()] = true
+				}
+			}) {
+				return nil
 			}
 		}
+		if result.err != nil {
+			return wrapAPIError(d, nil, result.err)
+		}
+	}
+	return nil
+}
+
+// searchLogPages requests the pages of the search, following their cursor until the
+// last page, and hands them to handlePage, which returns false to stop early.
+func searchLogPages(ctx context.Context, d *plugin.QueryData, client *rawClient, req logsSearchRequest, handlePage func(logs []datadog.Log) bool) error {
+	for {
+		release, err := acquireLogSearchSlot(ctx, d)
+		if err != nil {
+			return err
+		}
+		var resp logsSearchResponse
+		// https://docs.datadoghq.com/api/latest/logs/#search-logs
+		err = client.do(ctx, http.MethodPost, "/api/v2/logs/events/search", nil, req, &resp)
+		release()
+		if err != nil {
+			plugin.Logger(ctx).Error("datadog_log_event.searchLogPages", "query_error", err)
+			return err
+		}
+
+		if !handlePage(resp.Data) {
+			return nil
+		}
 
-		if resp.HasLinks() {
-			if resp.Links.HasNext() {
-				opts.WithPageCursor(*resp.Meta.GetPage().After)
-			} else {
-				break
+		if resp.Meta.Page.After == nil || *resp.Meta.Page.After == "" {
+			return nil
+		}
+		req.Page.Cursor = resp.Meta.Page.After
+	}
+}
+
+// streamLogEvents streams the logs, except for the IDs to skip, and calls streamed for
+// each streamed log when set. It returns false once the limit of the query is hit.
+func streamLogEvents(ctx context.Context, d *plugin.QueryData, logs []datadog.Log, req logsSearchRequest, index *string, skip map[string]bool, streamed func(log datadog.Log)) bool {
+	for _, log := range logs {
+		if skip[log.GetId()] {
+			continue
+		}
+		d.StreamListItem(ctx, &logEvent{Log: log, Index: index, StorageTier: req.Filter.StorageTier})
+		if streamed != nil {
+			streamed(log)
+		}
+		// Check if context has been cancelled or if the limit has been hit (if specified)
+		if d.RowsRemaining(ctx) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// logSearchAttributes are the columns whose quals are added to the search query, by the
+// attribute of the search syntax they filter, in the order they are added.
+var logSearchAttributes = []struct {
+	column    string
+	attribute string
+}{
+	{"service", "service"},
+	{"host", "host"},
+	{"status", "status"},
+	{"trace_id", "trace_id"},
+	{"env", "env"},
+}
+
+// logSearchQuery returns the query of the search, which is the query qual combined with
+// the = and <> quals of the logSearchAttributes columns, e.g.
+// (@http.method:POST) service:("api" OR "web") -status:"info".
+func logSearchQuery(d *plugin.QueryData) string {
+	var terms []string
+	for _, search := range logSearchAttributes {
+		if d.Quals[search.column] == nil {
+			continue
+		}
+		for _, q := range d.Quals[search.column].Quals {
+			values := qualValueStrings(q)
+			if len(values) == 0 {
+				continue
 			}
-		} else {
-			break
+			for i, value := range values {
+				values[i] = logSearchValue(value)
+			}
+			switch q.Operator {
+			case "=":
+				if len(values) == 1 {
+					terms = append(terms, search.attribute+":"+values[0])
+				} else {
+					terms = append(terms, search.attribute+":("+strings.Join(values, " OR ")+")")
+				}
+			case "<>":
+				for _, value := range values {
+					terms = append(terms, "-"+search.attribute+":"+value)
+				}
+			}
+		}
+	}
+
+	query := d.EqualsQualString("query")
+	if len(terms) == 0 {
+		return query
+	}
+	if query != "" {
+		terms = append([]string{"(" + query + ")"}, terms...)
+	}
+	return strings.Join(terms, " ")
+}
+
+// logSearchValue quotes the value for the search syntax, so it matches exactly even if
+// it has spaces, wildcards or other special characters.
+func logSearchValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// qualStrings returns the values of the = and IN quals of the column.
+func qualStrings(d *plugin.QueryData, column string) []string {
+	if d.Quals[column] == nil {
+		return nil
+	}
+	var values []string
+	for _, q := range d.Quals[column].Quals {
+		if q.Operator == "=" {
+			values = append(values, qualValueStrings(q)...)
+		}
+	}
+	return values
+}
+
+// qualValueStrings returns the value of the qual, or its values if it is a list, e.g.
+// for IN.
+func qualValueStrings(q *quals.Qual) []string {
+	if list := q.Value.GetListValue(); list != nil {
+		values := make([]string, 0, len(list.Values))
+		for _, value := range list.Values {
+			values = append(values, value.GetStringValue())
+		}
+		return values
+	}
+	return []string{q.Value.GetStringValue()}
+}
+
+//// TRANSFORM FUNCTIONS
+
+// logTitleLength is the number of characters of the message of a log kept in its title.
+const logTitleLength = 100
+
+// logEventTitle titles a log by the first line of its message, truncated, or else by
+// its service and host, or its ID if it has neither.
+func logEventTitle(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	event, ok := d.Value.(*logEvent)
+	if !ok {
+		return nil, nil
+	}
+	attributes := event.GetAttributes()
+
+	message, _, _ := strings.Cut(strings.TrimSpace(attributes.GetMessage()), "\n")
+	if message = strings.TrimSpace(message); message != "" {
+		if runes := []rune(message); len(runes) > logTitleLength {
+			message = string(runes[:logTitleLength]) + "..."
+		}
+		return message, nil
+	}
+
+	switch service, host := attributes.GetService(), attributes.GetHost(); {
+	case service != "" && host != "":
+		return service + " on " + host, nil
+	case service != "":
+		return service, nil
+	case host != "":
+		return host, nil
+	}
+	return event.GetId(), nil
+}
+
+// logAttributeField is where a column of a reserved or standard attribute is found in
+// a log: the first of the attribute paths the log has, e.g. "dd.trace_id", or else the
+// tag, if any.
+type logAttributeField struct {
+	paths []string
+	tag   string
+}
+
+// The reserved and standard attributes of logs, see
+// https://docs.datadoghq.com/logs/log_configuration/attributes_naming_convention/
+// Tracers add the trace correlation attributes under dd.
+var (
+	logTraceID        = logAttributeField{paths: []string{"trace_id", "dd.trace_id"}}
+	logSpanID         = logAttributeField{paths: []string{"span_id", "dd.span_id"}}
+	logEnv            = logAttributeField{paths: []string{"env", "dd.env"}, tag: "env"}
+	logVersion        = logAttributeField{paths: []string{"version", "dd.version"}, tag: "version"}
+	logSource         = logAttributeField{paths: []string{"source", "ddsource"}, tag: "source"}
+	logHTTPStatusCode = logAttributeField{paths: []string{"http.status_code"}}
+	logErrorKind      = logAttributeField{paths: []string{"error.kind"}}
+)
+
+// logAttribute returns the value of the logAttributeField given as the transform param.
+// Numbers are returned as strings without exponent, as IDs are often sent as numbers.
+func logAttribute(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	attributes, ok := d.Value.(*datadog.LogAttributes)
+	if !ok || attributes == nil {
+		return nil, nil
+	}
+	field := d.Param.(logAttributeField)
+
+	for _, path := range field.paths {
+		value, found := nestedAttribute(attributes.GetAttributes(), path)
+		if !found || value == nil {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		default:
+			return fmt.Sprint(v), nil
 		}
 	}
 
+	if field.tag != "" {
+		if values := parseTags(attributes.GetTags())[field.tag]; len(values) > 0 {
+			return values[0], nil
+		}
+	}
 	return nil, nil
 }
+
+// logAttributeInt converts the attribute string to an integer, e.g. for status codes
+// sent as strings. Values which are not integers are null.
+func logAttributeInt(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	value, ok := d.Value.(string)
+	if !ok {
+		return nil, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	return i, nil
+}
+
+// nestedAttribute returns the attribute at the dot separated path, e.g. http.status_code,
+// which may be nested objects or a key with dots.
+func nestedAttribute(attributes map[string]interface{}, path string) (interface{}, bool) {
+	if value, ok := attributes[path]; ok {
+		return value, true
+	}
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return nil, false
+	}
+	child, ok := attributes[key].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return nestedAttribute(child, rest)
+}
@@ -0,0 +1,330 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	datadog "github.com/DataDog/datadog-api-client-go/api/v2/datadog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func TestListLogEventsCursorPagination(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("POST", "/api/v2/logs/events/search", nil, fakeResponse{Fixture: "v2/logs_page0.json"}, fakeResponse{Fixture: "v2/logs_page1.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_log_event",
+		Columns: []string{"id", "timestamp", "service", "status", "host", "message", "tags", "attributes", "query"},
+		Quals:   stringQual("query", "=", "service:web"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "id")
+
+	if got, want := columnValues(rows, "id"), []interface{}{"AAAAAXdlog1", "AAAAAXdlog2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("id = %v, want %v", got, want)
+	}
+	row := rows[0]
+	if want := time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC); row["timestamp"] != want {
+		t.Errorf("timestamp = %v, want %v", row["timestamp"], want)
+	}
+	if row["query"] != "service:web" {
+		t.Errorf("query = %v, want the qual value", row["query"])
+	}
+	if want := map[string]interface{}{"http": map[string]interface{}{"status_code": float64(500)}}; !reflect.DeepEqual(row["attributes"], want) {
+		t.Errorf("attributes = %v, want %v", row["attributes"], want)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/events/search")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	for i, req := range requests {
+		body := searchRequestBody(t, req)
+		if body.Filter.Query != "service:web" {
+			t.Errorf("filter.query = %q, want it pushed down", body.Filter.Query)
+		}
+		if body.Filter.StorageTier != "indexes" || len(body.Filter.Indexes) != 0 {
+			t.Errorf("filter = %+v, want all indexes of the indexes tier", body.Filter)
+		}
+		if i == 1 && (body.Page.Cursor == nil || *body.Page.Cursor != "cursor-1") {
+			t.Errorf("page.cursor = %v, want cursor-1", body.Page.Cursor)
+		}
+	}
+}
+
+func TestListLogEventsTimestampQual(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("POST", "/api/v2/logs/events/search", nil, fakeResponse{Fixture: "v2/logs_page1.json"})
+
+	from := time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 2, 1, 11, 0, 0, 0, time.UTC)
+	quals := timestampQual("timestamp", ">=", from)
+	quals["timestamp"].Quals = append(quals["timestamp"].Quals, timestampQual("timestamp", "<", to)["timestamp"].Quals...)
+
+	if _, err := f.query(testQuery{
+		Table:   "datadog_log_event",
+		Columns: []string{"id", "timestamp"},
+		Quals:   map[string]*proto.Quals{"timestamp": quals["timestamp"]},
+	}); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/events/search")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	filter := searchRequestBody(t, requests[0]).Filter
+	gotFrom, err := time.Parse(time.RFC3339, filter.From)
+	if err != nil || !gotFrom.Equal(from) {
+		t.Errorf("filter.from = %q, want %v", filter.From, from)
+	}
+	gotTo, err := time.Parse(time.RFC3339, filter.To)
+	if err != nil || !gotTo.Equal(to) {
+		t.Errorf("filter.to = %q, want %v", filter.To, to)
+	}
+}
+
+func TestListLogEventsTimeSlices(t *testing.T) {
+	f := newFakeDatadog(t)
+	// Every slice returns the same log, as if it was at the boundary of the slices
+	f.handle("POST", "/api/v2/logs/events/search", nil, fakeResponse{Fixture: "v2/logs_page1.json"})
+	f.config = "log_slices = 2\nlog_max_concurrency = 1"
+
+	from := time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 2, 1, 11, 0, 0, 0, time.UTC)
+	quals := timestampQual("timestamp", ">=", from)
+	quals["timestamp"].Quals = append(quals["timestamp"].Quals, timestampQual("timestamp", "<", to)["timestamp"].Quals...)
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_log_event",
+		Columns: []string{"id", "timestamp"},
+		Quals:   map[string]*proto.Quals{"timestamp": quals["timestamp"]},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got, want := columnValues(rows, "id"), []interface{}{"AAAAAXdlog2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("id = %v, want %v once", got, want)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/events/search")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want one per slice", len(requests))
+	}
+	var slices []string
+	for _, req := range requests {
+		filter := searchRequestBody(t, req).Filter
+		slices = append(slices, filter.From+" - "+filter.To)
+	}
+	sort.Strings(slices)
+	want := []string{"2023-02-01T09:00:00Z - 2023-02-01T10:00:00Z", "2023-02-01T10:00:00Z - 2023-02-01T11:00:00Z"}
+	if !reflect.DeepEqual(slices, want) {
+		t.Errorf("slices = %v, want %v", slices, want)
+	}
+}
+
+func TestLogSlices(t *testing.T) {
+	from := time.Date(2023, 2, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		timeRange logTimeRange
+		want      int
+	}{
+		{"split", logTimeRange{from: from, to: from.Add(4 * time.Hour)}, 4},
+		{"no start", logTimeRange{to: from.Add(4 * time.Hour)}, 1},
+		{"single timestamp", logTimeRange{from: from, to: from}, 1},
+		{"shorter than the slices", logTimeRange{from: from, to: from.Add(150 * time.Second)}, 2},
+	}
+	slices := 4
+	d := &plugin.QueryData{Connection: &plugin.Connection{Config: datadogConfig{LogSlices: &slices}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := logSlices(d, tt.timeRange)
+			if len(got) != tt.want {
+				t.Fatalf("got %d slices, want %d", len(got), tt.want)
+			}
+			if !got[0].from.Equal(tt.timeRange.from) || !got[len(got)-1].to.Equal(tt.timeRange.to) {
+				t.Errorf("slices %v do not cover %v", got, tt.timeRange)
+			}
+			for i := 1; i < len(got); i++ {
+				if !got[i].from.Equal(got[i-1].to) {
+					t.Errorf("slice %d starts at %v, want %v", i, got[i].from, got[i-1].to)
+				}
+			}
+		})
+	}
+}
+
+func TestListLogEventsAttributeQuals(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("POST", "/api/v2/logs/events/search", nil, fakeResponse{Fixture: "v2/logs_page1.json"})
+
+	if _, err := f.query(testQuery{
+		Table:   "datadog_log_event",
+		Columns: []string{"id", "service", "host", "status"},
+		Quals: mergeQuals(
+			stringQual("query", "=", "@http.method:GET OR @http.method:HEAD"),
+			listQual("service", "web", "checkout api"),
+			listQual("host", `web-"2"`, `web\3`),
+			stringQual("status", "<>", "debug"),
+		),
+	}); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/events/search")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	want := `(@http.method:GET OR @http.method:HEAD) service:("web" OR "checkout api") host:("web-\"2\"" OR "web\\3") -status:"debug"`
+	if got := searchRequestBody(t, requests[0]).Filter.Query; got != want {
+		t.Errorf("filter.query = %s, want %s", got, want)
+	}
+}
+
+func TestListLogEventsReservedAttributes(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("POST", "/api/v2/logs/events/search", nil, fakeResponse{Body: `{"data": [{"type": "log", "id": "AAAAAXdlog3", "attributes": {
+		"timestamp": "2023-02-01T10:00:00.000Z",
+		"service": "web",
+		"tags": ["env:prod", "version:1.2.0", "source:nginx", "team:web"],
+		"attributes": {
+			"dd": {"trace_id": "1234567890123456789", "span_id": 42, "env": "staging"},
+			"http": {"status_code": "504"},
+			"error.kind": "TimeoutError"
+		}
+	}}], "meta": {}}`})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_log_event",
+		Columns: []string{"id", "trace_id", "span_id", "env", "version", "source", "http_status_code", "error_kind", "team"},
+		Quals: mergeQuals(
+			stringQual("trace_id", "=", "1234567890123456789"),
+			stringQual("env", "=", "staging"),
+			stringQual("service", "=", "web"),
+		),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	want := map[string]interface{}{
+		"id":               "AAAAAXdlog3",
+		"trace_id":         "1234567890123456789",
+		"span_id":          "42",
+		"env":              "staging",
+		"version":          "1.2.0",
+		"source":           "nginx",
+		"http_status_code": int64(504),
+		"error_kind":       "TimeoutError",
+		"team":             "web",
+	}
+	for column, value := range want {
+		if rows[0][column] != value {
+			t.Errorf("%s = %v, want %v", column, rows[0][column], value)
+		}
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/events/search")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	query := searchRequestBody(t, requests[0]).Filter.Query
+	if want := `service:"web" trace_id:"1234567890123456789" env:"staging"`; query != want {
+		t.Errorf("filter.query = %s, want %s", query, want)
+	}
+}
+
+func TestListLogEventsIndexQuals(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("POST", "/api/v2/logs/events/search", nil, fakeResponse{Fixture: "v2/logs_page1.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_log_event",
+		Columns: []string{"id", "index", "storage_tier", "time_zone"},
+		Quals: mergeQuals(
+			listQual("index", "main", "payments"),
+			stringQual("storage_tier", "=", "flex"),
+			stringQual("time_zone", "=", "Europe/Paris"),
+		),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/logs/events/search")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want one per index", len(requests))
+	}
+	var searched []string
+	for _, req := range requests {
+		body := searchRequestBody(t, req)
+		searched = append(searched, body.Filter.Indexes...)
+		if body.Filter.StorageTier != "flex" || body.Options == nil || body.Options.Timezone != "Europe/Paris" {
+			t.Errorf("body = %+v, want the storage tier and time zone", body)
+		}
+	}
+	sort.Strings(searched)
+	if want := []string{"main", "payments"}; !reflect.DeepEqual(searched, want) {
+		t.Errorf("indexes = %v, want %v", searched, want)
+	}
+
+	if got, want := columnValues(sortRows(rows, "index"), "index"), []interface{}{"main", "payments"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("index = %v, want %v", got, want)
+	}
+	for _, row := range rows {
+		if row["storage_tier"] != "flex" || row["time_zone"] != "Europe/Paris" {
+			t.Errorf("storage_tier, time_zone = %v, %v", row["storage_tier"], row["time_zone"])
+		}
+	}
+}
+
+// searchRequestBody decodes the body of a log search request.
+func searchRequestBody(t *testing.T, req recordedRequest) logsSearchRequest {
+	t.Helper()
+	var body logsSearchRequest
+	if err := json.Unmarshal(req.Body, &body); err != nil {
+		t.Fatalf("invalid search request body %s: %v", req.Body, err)
+	}
+	return body
+}
+
+func TestLogEventTitle(t *testing.T) {
+	longMessage := strings.Repeat("x", logTitleLength+20)
+	tests := []struct {
+		name       string
+		attributes datadog.LogAttributes
+		want       interface{}
+	}{
+		{"first line of the message", datadog.LogAttributes{Message: datadog.PtrString("GET /checkout 500\nstack trace"), Service: datadog.PtrString("web")}, "GET /checkout 500"},
+		{"truncated message", datadog.LogAttributes{Message: datadog.PtrString(longMessage)}, longMessage[:logTitleLength] + "..."},
+		{"service and host", datadog.LogAttributes{Service: datadog.PtrString("web"), Host: datadog.PtrString("web-1")}, "web on web-1"},
+		{"host", datadog.LogAttributes{Host: datadog.PtrString("web-1")}, "web-1"},
+		{"id", datadog.LogAttributes{}, "AAAAAXdlog1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributes := tt.attributes
+			event := &logEvent{Log: datadog.Log{Id: datadog.PtrString("AAAAAXdlog1"), Attributes: &attributes}}
+			got, err := logEventTitle(context.Background(), &transform.TransformData{Value: event})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("title = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -14,12 +14,17 @@ func tableDatadogLogsMetric(ctx context.Context) *plugin.Table {
 		Description: "Log-based metrics are a cost-efficient way to summarize log data from the entire ingest stream.",
 		Get: &plugin.GetConfig{
 			Hydrate:    getLogsMetric,
+			Tags:       apiFamilyTags("logs_config"),
 			KeyColumns: plugin.SingleColumn("id"),
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundError,
+			},
 		},
 		List: &plugin.ListConfig{
 			Hydrate: listLogsMetrics,
+			Tags:    apiFamilyTags("logs_config"),
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns([]*plugin.Column{
 			// Top columns
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "The name of the log-based metric."},
 			{Name: "compute_aggregation_type", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Compute.AggregationType"), Description: "The type of aggregation to used for computing metric. Can be one of \"count\", \"distribution\"."},
@@ -28,7 +33,11 @@ func tableDatadogLogsMetric(ctx context.Context) *plugin.Table {
 
 			// JSON columns
 			{Name: "group_by", Type: proto.ColumnType_JSON, Transform: transform.FromField("Attributes.GroupBy"), Description: "List of rules for the group by."},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Id"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, resourceLink{Resource: "logs_metric", IDField: "Id"}), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+		}),
 	}
 }
 
@@ -40,10 +49,10 @@ func listLogsMetrics(ctx context.Context, d *plugin.QueryData, _ *plugin.Hydrate
 	}
 
 	// https://github.com/DataDog/datadog-api-client-go/blob/master/api/v2/datadog/docs/LogsMetricsApi.md#ListLogsMetrics
-	resp, _, err := apiClient.LogsMetricsApi.ListLogsMetrics(ctx)
+	resp, httpResp, err := apiClient.LogsMetricsApi.ListLogsMetrics(ctx)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_logs_metric.listLogsMetrics", "query_error", err)
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	for _, logMetric := range resp.GetData() {
@@ -66,13 +75,10 @@ func getLogsMetric(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateDa
 	}
 
 	// https://github.com/DataDog/datadog-api-client-go/blob/master/api/v2/datadog/docs/LogsMetricsApi.md#GetLogsMetric
-	resp, _, err := apiClient.LogsMetricsApi.GetLogsMetric(ctx, metricID)
+	resp, httpResp, err := apiClient.LogsMetricsApi.GetLogsMetric(ctx, metricID)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_logs_metric.getLogsMetric", "query_error", err)
-		if err.Error() == "404 Not Found" {
-			return nil, nil
-		}
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	return resp.GetData(), nil
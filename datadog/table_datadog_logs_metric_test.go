@@ -0,0 +1,63 @@
+package datadog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListLogsMetrics(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/logs/config/metrics", nil, fakeResponse{Fixture: "v2/logs_metrics.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_logs_metric",
+		Columns: []string{"id", "compute_aggregation_type", "compute_path", "filter_query", "group_by"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "id")
+
+	if got, want := columnValues(rows, "id"), []interface{}{"api.latency", "nginx.errors"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("id = %v, want %v", got, want)
+	}
+	if got, want := columnValues(rows, "compute_path"), []interface{}{"@duration", nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("compute_path = %v, want %v", got, want)
+	}
+	if rows[1]["filter_query"] != "service:nginx status:error" {
+		t.Errorf("filter_query = %v", rows[1]["filter_query"])
+	}
+	if groupBy, ok := rows[1]["group_by"].([]interface{}); !ok || len(groupBy) != 1 {
+		t.Errorf("group_by = %v", rows[1]["group_by"])
+	}
+}
+
+func TestGetLogsMetric(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/logs/config/metrics/api.latency", nil, fakeResponse{Fixture: "v2/logs_metric.json"})
+	f.handle("GET", "/api/v2/logs/config/metrics/missing", nil, fakeResponse{Status: 404, Body: `{"errors": ["Not found"]}`})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_logs_metric",
+		Columns: []string{"id", "compute_aggregation_type"},
+		Quals:   stringQual("id", "=", "api.latency"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["compute_aggregation_type"] != "distribution" {
+		t.Errorf("got rows %v", rows)
+	}
+
+	rows, err = f.query(testQuery{
+		Table:   "datadog_logs_metric",
+		Columns: []string{"id"},
+		Quals:   stringQual("id", "=", "missing"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want 0", len(rows))
+	}
+}
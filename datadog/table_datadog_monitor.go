@@ -9,17 +9,21 @@ import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// monitorLink builds the akas and app_url of the monitor rows.
+var monitorLink = resourceLink{Resource: "monitor", IDField: "Id", AppPath: "/monitors/%s"}
+
 func tableDatadogMonitor(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_monitor",
 		Description: "A monitor provides alerts and notifications if a specific metric is above or below a certain threshold.",
 		List: &plugin.ListConfig{
 			Hydrate: listMonitors,
+			Tags:    apiFamilyTags("monitors"),
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "name", Require: plugin.Optional},
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns(append([]*plugin.Column{
 			// Top columns
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the monitor."},
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "ID of the monitor."},
@@ -40,7 +44,12 @@ func tableDatadogMonitor(ctx context.Context) *plugin.Table {
 			{Name: "restricted_roles", Type: proto.ColumnType_JSON, Description: "Relationships of the user object returned by the API."},
 			{Name: "group_states", Type: proto.ColumnType_JSON, Transform: transform.FromField("State.Groups"), Description: "Dictionary where the keys are groups (comma separated lists of tags) and the values are the list of groups your monitor is broken down on."},
 			{Name: "tags", Type: proto.ColumnType_JSON, Description: "Tags associated to monitor."},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Name"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, monitorLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, monitorLink), Description: "Link to the monitor in the Datadog app of the site."},
+		}, tagColumns("Tags", "team", "env", "service")...)),
 	}
 }
 
@@ -66,27 +75,27 @@ func listMonitors(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateDat
 		opts.WithName(name)
 	}
 
+	// The number of monitors is not returned, so the pages are fetched one after
+	// another until a page shorter than the page size
 	for {
-		resp, _, err := apiClient.MonitorsApi.ListMonitors(ctx, opts)
+		resp, httpResp, err := apiClient.MonitorsApi.ListMonitors(ctx, opts)
 		if err != nil {
 			plugin.Logger(ctx).Error("datadog_monitor.listMonitors", "query_error", err)
-			return nil, err
+			return nil, wrapAPIError(d, httpResp, err)
 		}
 
 		for _, monitor := range resp {
 			d.StreamListItem(ctx, monitor)
 			// Check if context has been cancelled or if the limit has been hit (if specified)
 			if d.RowsRemaining(ctx) == 0 {
-				break
+				return nil, nil
 			}
 		}
 
 		if len(resp) < int(pageSize) {
 			break
 		}
-
-		pageOffset := *opts.Page + 1
-		opts.Page = &pageOffset
+		opts.Page = datadog.PtrInt64(*opts.Page + 1)
 	}
 
 	return nil, nil
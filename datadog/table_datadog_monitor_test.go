@@ -0,0 +1,150 @@
+package datadog
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// monitorsPage returns a page of n generated monitors.
+func monitorsPage(n int) string {
+	monitors := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		monitors = append(monitors, fmt.Sprintf(`{"id": %d, "name": "Monitor %03d", "type": "metric alert", "query": "avg(last_5m):avg:system.load.1{*} > 1"}`, i+1, i))
+	}
+	return "[" + strings.Join(monitors, ",") + "]"
+}
+
+func TestListMonitorsPagination(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/monitor", url.Values{"page": {"0"}}, fakeResponse{Body: monitorsPage(100)})
+	f.handle("GET", "/api/v1/monitor", url.Values{"page": {"1"}}, fakeResponse{Fixture: "v1/monitors.json"})
+
+	rows, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id", "name"}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 101 {
+		t.Errorf("got %d rows, want 101", len(rows))
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/monitor")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	for i, req := range requests {
+		if got := req.URL.Query().Get("page"); got != fmt.Sprint(i) {
+			t.Errorf("request %d: page = %q, want %d", i, got, i)
+		}
+		if got := req.URL.Query().Get("page_size"); got != "100" {
+			t.Errorf("request %d: page_size = %q, want 100", i, got)
+		}
+	}
+}
+
+func TestListMonitorsNameQual(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/monitor", nil, fakeResponse{Fixture: "v1/monitors.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_monitor",
+		Columns: []string{"id", "name", "creator_email", "created_at", "priority", "tags", "tags_map", "team", "env", "service", "group_states", "title", "akas", "app_url"},
+		Quals:   stringQual("name", "=", "High CPU on web hosts"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/monitor")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if got := requests[0].URL.Query().Get("name"); got != "High CPU on web hosts" {
+		t.Errorf("name param = %q, want it pushed down", got)
+	}
+
+	row := rows[0]
+	if row["id"] != "1001" {
+		t.Errorf("id = %v, want 1001", row["id"])
+	}
+	if row["creator_email"] != "jane@example.com" {
+		t.Errorf("creator_email = %v", row["creator_email"])
+	}
+	if want := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC); row["created_at"] != want {
+		t.Errorf("created_at = %v, want %v", row["created_at"], want)
+	}
+	if row["priority"] != int64(2) {
+		t.Errorf("priority = %v, want 2", row["priority"])
+	}
+	if want := []interface{}{"env:prod", "team:web"}; !reflect.DeepEqual(row["tags"], want) {
+		t.Errorf("tags = %v, want %v", row["tags"], want)
+	}
+	if want := map[string]interface{}{"env": []interface{}{"prod"}, "team": []interface{}{"web"}}; !reflect.DeepEqual(row["tags_map"], want) {
+		t.Errorf("tags_map = %v, want %v", row["tags_map"], want)
+	}
+	if row["team"] != "web" || row["env"] != "prod" || row["service"] != nil {
+		t.Errorf("team, env, service = %v, %v, %v, want web, prod, nil", row["team"], row["env"], row["service"])
+	}
+	if groups, ok := row["group_states"].(map[string]interface{}); !ok || groups["host:web-1"] == nil {
+		t.Errorf("group_states = %v", row["group_states"])
+	}
+	if row["title"] != "High CPU on web hosts" {
+		t.Errorf("title = %v", row["title"])
+	}
+	// The fake server is not a Datadog site, so the site is its host and there is no app URL
+	if want := []interface{}{"datadog://" + f.server.Listener.Addr().String() + "/monitor/1001"}; !reflect.DeepEqual(row["akas"], want) {
+		t.Errorf("akas = %v, want %v", row["akas"], want)
+	}
+	if row["app_url"] != nil {
+		t.Errorf("app_url = %v, want nil", row["app_url"])
+	}
+}
+
+func TestListMonitorsRetriesThrottledRequests(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/monitor", nil,
+		fakeResponse{Status: 429, Header: map[string]string{"X-RateLimit-Reset": "0"}, Body: `{"errors": ["Rate limit exceeded"]}`},
+		fakeResponse{Fixture: "v1/monitors.json"},
+	)
+
+	rows, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Errorf("got %d rows, want 1", len(rows))
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/monitor")
+	if len(requests) != 2 || requests[0].StatusCode != 429 || requests[1].StatusCode != 200 {
+		t.Errorf("got requests %v, want a 429 followed by a 200", requests)
+	}
+}
+
+func TestListMonitorsRateLimitsConfig(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/monitor", nil, fakeResponse{Fixture: "v1/monitors.json"})
+	f.config = "rate_limits = { monitors = 100 }"
+
+	if _, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}}); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	f.config = "rate_limits = { monitor = 100 }"
+	_, err := f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}})
+	if err == nil || !strings.Contains(err.Error(), `invalid API family "monitor"`) {
+		t.Errorf("error = %v, want the API family to be rejected", err)
+	}
+
+	f.config = "rate_limits = { monitors = 0 }"
+	_, err = f.query(testQuery{Table: "datadog_monitor", Columns: []string{"id"}})
+	if err == nil || !strings.Contains(err.Error(), "rate_limits of monitors must be") {
+		t.Errorf("error = %v, want the rate of 0 to be rejected", err)
+	}
+}
@@ -14,8 +14,9 @@ func tableDatadogPermission(ctx context.Context) *plugin.Table {
 		Description: "Permissions provide the base level of access for roles.",
 		List: &plugin.ListConfig{
 			Hydrate: listPermissions,
+			Tags:    apiFamilyTags("users"),
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns([]*plugin.Column{
 			// Top columns
 			{Name: "name", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Name"), Description: "Name of the permission."},
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "Id of the permission."},
@@ -27,7 +28,11 @@ func tableDatadogPermission(ctx context.Context) *plugin.Table {
 			{Name: "description", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Description"), Description: "Description of the permission."},
 			{Name: "display_name", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.DisplayName"), Description: "Displayed name for the permission."},
 			{Name: "display_type", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.DisplayType"), Description: "Displayed type the permission."},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Name"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, resourceLink{Resource: "permission", IDField: "Id"}), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+		}),
 	}
 }
 
@@ -39,10 +44,10 @@ func listPermissions(ctx context.Context, d *plugin.QueryData, _ *plugin.Hydrate
 	}
 
 	// https: //github.com/DataDog/datadog-api-client-go/blob/master/api/v2/datadog/docs/RolesApi.md#ListPermissions
-	resp, _, err := apiClient.RolesApi.ListPermissions(ctx)
+	resp, httpResp, err := apiClient.RolesApi.ListPermissions(ctx)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_permission.listPermissions", "query_error", err)
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	for _, permission := range resp.GetData() {
@@ -0,0 +1,48 @@
+package datadog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListPermissions(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/permissions", nil, fakeResponse{Fixture: "v2/permissions.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_permission",
+		Columns: []string{"name", "id", "restricted", "group_name", "created_at", "org_name", "org_public_id"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "name")
+
+	if got, want := columnValues(rows, "name"), []interface{}{"logs_read_data", "monitors_read"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("name = %v, want %v", got, want)
+	}
+	if got, want := columnValues(rows, "restricted"), []interface{}{false, true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("restricted = %v, want %v", got, want)
+	}
+	if got, want := rows[0]["created_at"], time.Date(2018, 10, 19, 15, 35, 23, 734317000, time.UTC); got != want {
+		t.Errorf("created_at = %v, want %v", got, want)
+	}
+	if got := rows[1]["org_name"]; got != "Test Org" {
+		t.Errorf("org_name = %v, want Test Org", got)
+	}
+	if got := rows[1]["org_public_id"]; got != "abc123def" {
+		t.Errorf("org_public_id = %v, want abc123def", got)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/permissions")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if got := requests[0].Header.Get("DD-API-KEY"); got != "test-api-key" {
+		t.Errorf("DD-API-KEY = %q, want test-api-key", got)
+	}
+	if got, want := requests[0].URL.String(), f.server.URL+"/api/v2/permissions"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+}
@@ -10,21 +10,35 @@ import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// roleLink builds the akas and app_url of the role rows.
+var roleLink = resourceLink{Resource: "role", IDField: "Id", AppPath: "/organization-settings/roles/%s"}
+
 func tableDatadogRole(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_role",
 		Description: "Roles categorize users and define what account permissions those users have.",
 		Get: &plugin.GetConfig{
 			Hydrate:    getRole,
+			Tags:       apiFamilyTags("users"),
 			KeyColumns: plugin.SingleColumn("id"),
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundError,
+			},
 		},
 		List: &plugin.ListConfig{
 			Hydrate: listRoles,
+			Tags:    map[string]string{"api_family": "users", "pagination": "concurrent"},
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "name", Require: plugin.Optional},
 			},
 		},
-		Columns: []*plugin.Column{
+		HydrateConfig: []plugin.HydrateConfig{
+			{
+				Func: listRoleUsers,
+				Tags: apiFamilyTags("users"),
+			},
+		},
+		Columns: commonColumns([]*plugin.Column{
 			// Top columns
 			{Name: "name", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Name"), Description: "Name of the role."},
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "Id of the role."},
@@ -35,7 +49,12 @@ func tableDatadogRole(ctx context.Context) *plugin.Table {
 			// JSON column
 			{Name: "users", Type: proto.ColumnType_JSON, Hydrate: listRoleUsers, Transform: transform.From(userList), Description: "Set of objects containing the permission ID and the name of the permissions granted to this role."},
 			{Name: "permissions", Type: proto.ColumnType_JSON, Transform: transform.FromField("Relationships.Permissions.Data"), Description: "List of users emails attached to role."},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Name"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, roleLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, roleLink), Description: "Link to the role in the Datadog app of the site."},
+		}),
 	}
 }
 
@@ -57,36 +76,43 @@ func listRoles(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData)
 		opts.WithFilter(name)
 	}
 
-	count := int64(0)
-	for {
-		resp, _, err := apiClient.RolesApi.ListRoles(ctx, opts)
-		if err != nil {
-			plugin.Logger(ctx).Error("datadog_role.listRoles", "query_error", err)
-			return nil, err
-		}
+	resp, httpResp, err := apiClient.RolesApi.ListRoles(ctx, opts)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_role.listRoles", "query_error", err)
+		return nil, wrapAPIError(d, httpResp, err)
+	}
 
+	streamPage := func(resp datadog.RolesResponse) bool {
 		for _, role := range resp.GetData() {
-			count++
 			d.StreamListItem(ctx, role)
 			// Check if context has been cancelled or if the limit has been hit (if specified)
 			if d.RowsRemaining(ctx) == 0 {
-				return nil, nil
+				return false
 			}
 		}
+		return true
+	}
+	if !streamPage(resp) {
+		return nil, nil
+	}
 
-		// Break loop if using filter
-		if resp.Meta.Page.HasTotalFilteredCount() {
-			if count >= resp.Meta.Page.GetTotalFilteredCount() {
-				return nil, nil
-			}
-		}
-		// Break loop if not using filter
-		if count >= resp.Meta.Page.GetTotalCount() {
-			return nil, nil
-		}
-		opts.WithPageNumber(*opts.PageNumber + 1)
+	// The first page tells how many roles there are, so fetch the other pages concurrently
+	total := resp.Meta.Page.GetTotalCount()
+	if resp.Meta.Page.HasTotalFilteredCount() {
+		total = resp.Meta.Page.GetTotalFilteredCount()
+	}
+	err = listPagesConcurrently(ctx, d, 1, lastPageNumber(total, *opts.PageSize), func(ctx context.Context, page int64) (datadog.RolesResponse, error) {
+		pageOpts := opts
+		pageOpts.PageNumber = datadog.PtrInt64(page)
+		resp, httpResp, err := apiClient.RolesApi.ListRoles(ctx, pageOpts)
+		return resp, wrapAPIError(d, httpResp, err)
+	}, streamPage)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_role.listRoles", "query_error", err)
+		return nil, wrapAPIError(d, nil, err)
 	}
 
+	return nil, nil
 }
 
 func getRole(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
@@ -109,13 +135,10 @@ func getRole(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (i
 	}
 
 	// https://github.com/DataDog/datadog-api-client-go/blob/master/api/v2/datadog/docs/RolesApi.md#GetRole
-	resp, _, err := apiClient.RolesApi.GetRole(ctx, roleID)
+	resp, httpResp, err := apiClient.RolesApi.GetRole(ctx, roleID)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_role.getRole", "query_error", err)
-		if err.Error() == "404 Not Found" {
-			return nil, nil
-		}
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	return resp.GetData(), nil
@@ -140,9 +163,10 @@ func listRoleUsers(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateDa
 	var users []datadog.User
 
 	for paging {
-		resp, _, err := apiClient.RolesApi.ListRoleUsers(ctx, *role.Id, opts)
+		resp, httpResp, err := apiClient.RolesApi.ListRoleUsers(ctx, *role.Id, opts)
 		if err != nil {
 			plugin.Logger(ctx).Error("datadog_role.listRoleUsers", "query_error", err)
+			return nil, wrapAPIError(d, httpResp, err)
 		}
 
 		noOfUsers := len(resp.GetData())
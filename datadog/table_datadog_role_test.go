@@ -0,0 +1,74 @@
+package datadog
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListRoles(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/roles", nil, fakeResponse{Fixture: "v2/roles.json"})
+	f.handle("GET", "/api/v2/roles/role-admin/users", nil, fakeResponse{Fixture: "v2/role_users.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_role",
+		Columns: []string{"id", "name", "user_count", "users", "permissions"},
+		Quals:   stringQual("name", "=", "Datadog Admin Role"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	row := rows[0]
+	if row["user_count"] != int64(1) {
+		t.Errorf("user_count = %v, want 1", row["user_count"])
+	}
+	if want := []interface{}{"alice@example.com"}; !reflect.DeepEqual(row["users"], want) {
+		t.Errorf("users = %v, want %v", row["users"], want)
+	}
+	if permissions, ok := row["permissions"].([]interface{}); !ok || len(permissions) != 1 {
+		t.Errorf("permissions = %v", row["permissions"])
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/roles")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if got := requests[0].URL.Query().Get("filter"); got != "Datadog Admin Role" {
+		t.Errorf("filter = %q, want the name pushed down", got)
+	}
+}
+
+func TestGetRole(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/roles/role-admin", nil, fakeResponse{Fixture: "v2/role.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_role",
+		Columns: []string{"id", "name"},
+		Quals:   stringQual("id", "=", "role-admin"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["name"] != "Datadog Admin Role" {
+		t.Errorf("got rows %v, want the admin role", rows)
+	}
+}
+
+func TestListRolesForbidden(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/roles", nil, fakeResponse{Status: 403, Body: `{"errors": ["Forbidden"]}`})
+
+	_, err := f.query(testQuery{Table: "datadog_role", Columns: []string{"id"}})
+	if err == nil {
+		t.Fatal("query succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "user_access_read") {
+		t.Errorf("error %q does not name the missing scope", err)
+	}
+}
@@ -7,20 +7,29 @@ import (
 	datadog "github.com/DataDog/datadog-api-client-go/api/v2/datadog"
 	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// securityMonitoringRuleLink builds the akas and app_url of the security monitoring rule rows.
+var securityMonitoringRuleLink = resourceLink{Resource: "security_monitoring_rule", IDField: "Id", AppPath: "/security/configuration/rules/view/%s"}
+
 func tableDatadogSecurityMonitoringRule(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_security_monitoring_rule",
 		Description: "Security monitoring rules define conditional logic that is applied to all ingested logs and cloud configurations.",
 		Get: &plugin.GetConfig{
 			Hydrate:    getSecurityMonitoringRule,
+			Tags:       apiFamilyTags("security_monitoring"),
 			KeyColumns: plugin.SingleColumn("id"),
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundError,
+			},
 		},
 		List: &plugin.ListConfig{
 			Hydrate: listSecurityMonitoringRules,
+			Tags:    map[string]string{"api_family": "security_monitoring", "pagination": "concurrent"},
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns(append([]*plugin.Column{
 			// Top columns
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "The ID of the rule."},
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "The name of the rule."},
@@ -41,7 +50,12 @@ func tableDatadogSecurityMonitoringRule(ctx context.Context) *plugin.Table {
 			{Name: "filters", Type: proto.ColumnType_JSON, Description: "Additional queries to filter matched events before they are processed."},
 			{Name: "options", Type: proto.ColumnType_JSON, Description: "Additional options for security monitoring rules."},
 			{Name: "tags", Type: proto.ColumnType_JSON, Description: "Tags for generated signals."},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Name"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, securityMonitoringRuleLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, securityMonitoringRuleLink), Description: "Link to the rule in the Datadog app of the site."},
+		}, tagColumns("Tags", "team", "env", "service")...)),
 	}
 }
 
@@ -58,29 +72,40 @@ func listSecurityMonitoringRules(ctx context.Context, d *plugin.QueryData, _ *pl
 		PageNumber: datadog.PtrInt64(0),
 	}
 
-	count := int64(0)
-	for {
-		resp, _, err := apiClient.SecurityMonitoringApi.ListSecurityMonitoringRules(ctx, opts)
-		if err != nil {
-			plugin.Logger(ctx).Error("datadog_security_monitoring_rule.listSecurityMonitoringRules", "query_error", err)
-			return nil, err
-		}
+	resp, httpResp, err := apiClient.SecurityMonitoringApi.ListSecurityMonitoringRules(ctx, opts)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_security_monitoring_rule.listSecurityMonitoringRules", "query_error", err)
+		return nil, wrapAPIError(d, httpResp, err)
+	}
 
+	streamPage := func(resp datadog.SecurityMonitoringListRulesResponse) bool {
 		for _, securityMonitoringRule := range resp.GetData() {
-			count++
 			d.StreamListItem(ctx, securityMonitoringRule)
 			// Check if context has been cancelled or if the limit has been hit (if specified)
 			if d.RowsRemaining(ctx) == 0 {
-				return nil, nil
+				return false
 			}
 		}
+		return true
+	}
+	if !streamPage(resp) {
+		return nil, nil
+	}
 
-		if count >= resp.Meta.Page.GetTotalCount() {
-			return nil, nil
-		}
-		opts.WithPageNumber(*opts.PageNumber + 1)
+	// The first page tells how many rules there are, so fetch the other pages concurrently
+	total := resp.Meta.Page.GetTotalCount()
+	err = listPagesConcurrently(ctx, d, 1, lastPageNumber(total, *opts.PageSize), func(ctx context.Context, page int64) (datadog.SecurityMonitoringListRulesResponse, error) {
+		pageOpts := opts
+		pageOpts.PageNumber = datadog.PtrInt64(page)
+		resp, httpResp, err := apiClient.SecurityMonitoringApi.ListSecurityMonitoringRules(ctx, pageOpts)
+		return resp, wrapAPIError(d, httpResp, err)
+	}, streamPage)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_security_monitoring_rule.listSecurityMonitoringRules", "query_error", err)
+		return nil, wrapAPIError(d, nil, err)
 	}
 
+	return nil, nil
 }
 
 func getSecurityMonitoringRule(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
@@ -103,13 +128,10 @@ func getSecurityMonitoringRule(ctx context.Context, d *plugin.QueryData, h *plug
 	}
 
 	// https://github.com/DataDog/datadog-api-client-go/blob/master/api/v2/datadog/docs/SecurityMonitoringApi.md#getsecuritymonitoringrule
-	resp, _, err := apiClient.SecurityMonitoringApi.GetSecurityMonitoringRule(ctx, ruleID)
+	resp, httpResp, err := apiClient.SecurityMonitoringApi.GetSecurityMonitoringRule(ctx, ruleID)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_security_monitoring_rule.getRole", "query_error", err)
-		if err.Error() == "404 Not Found" {
-			return nil, nil
-		}
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	return resp, nil
@@ -0,0 +1,57 @@
+package datadog
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListSecurityMonitoringRulesPagination(t *testing.T) {
+	f := newFakeDatadog(t)
+	// The pages claim 150 rules, so the page after the first is requested too
+	f.handle("GET", "/api/v2/security_monitoring/rules", url.Values{"page[number]": {"0"}}, fakeResponse{Fixture: "v2/security_rules_page0.json"})
+	f.handle("GET", "/api/v2/security_monitoring/rules", url.Values{"page[number]": {"1"}}, fakeResponse{Fixture: "v2/security_rules_page1.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_security_monitoring_rule",
+		Columns: []string{"id", "name", "is_enabled", "version", "tags", "cases"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "id")
+
+	if got, want := columnValues(rows, "name"), []interface{}{"Brute force login", "Root login"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("name = %v, want %v", got, want)
+	}
+	if got, want := columnValues(rows, "is_enabled"), []interface{}{true, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("is_enabled = %v, want %v", got, want)
+	}
+	if rows[0]["version"] != int64(3) {
+		t.Errorf("version = %v, want 3", rows[0]["version"])
+	}
+}
+
+func TestListSecurityMonitoringRulesForbidden(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/security_monitoring/rules", nil, fakeResponse{Status: 403, Body: `{"errors": ["Forbidden"]}`})
+
+	// 403s are ignored by default, as orgs without Cloud SIEM get them
+	rows, err := f.query(testQuery{Table: "datadog_security_monitoring_rule", Columns: []string{"id"}})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want 0", len(rows))
+	}
+
+	f.config = "ignore_error_codes = []"
+	_, err = f.query(testQuery{Table: "datadog_security_monitoring_rule", Columns: []string{"id"}})
+	if err == nil {
+		t.Fatal("query succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "security_monitoring_rules_read") {
+		t.Errorf("error %q does not name the missing scope", err)
+	}
+}
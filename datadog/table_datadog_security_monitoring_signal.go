@@ -10,19 +10,23 @@ import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// securityMonitoringSignalLink builds the akas and app_url of the security monitoring signal rows.
+var securityMonitoringSignalLink = resourceLink{Resource: "security_monitoring_signal", IDField: "Id", AppPath: "/security?event=%s"}
+
 func tableDatadogSecurityMonitoringSignal(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_security_monitoring_signal",
 		Description: "Signals are threats detected based on a security monitoring rule.",
 		List: &plugin.ListConfig{
 			Hydrate: listSecurityMonitoringSignals,
+			Tags:    apiFamilyTags("security_monitoring"),
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "timestamp", Operators: []string{">", ">=", "=", "<", "<="}, Require: plugin.Optional},
 				{Name: "filter_query", Require: plugin.Optional},
 			},
 		},
 
-		Columns: []*plugin.Column{
+		Columns: commonColumns(append([]*plugin.Column{
 			// Top columns
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "The unique ID of the security signal."},
 			{Name: "message", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Message"), Description: "The message in the security signal defined by the rule that generated the signal."},
@@ -33,12 +37,16 @@ func tableDatadogSecurityMonitoringSignal(ctx context.Context) *plugin.Table {
 			// JSON columns
 			{Name: "attributes", Type: proto.ColumnType_JSON, Transform: transform.FromField("Attributes.Attributes"), Description: "A JSON object of attributes in the security signal."},
 			{Name: "tags", Type: proto.ColumnType_JSON, Transform: transform.FromField("Attributes.Tags"), Description: "An array of tags associated with the security signal."},
-		},
+
+			// Steampipe standard columns
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, securityMonitoringSignalLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, securityMonitoringSignalLink), Description: "Link to the security signal in the Datadog app of the site."},
+		}, tagColumns("Attributes.Tags", "team", "env", "service")...)),
 	}
 }
 
 func listSecurityMonitoringSignals(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
-	ctx, _, configuration, err := connectV2(ctx, d)
+	ctx, apiClient, _, err := connectV2(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_security_monitoring_signal.listSecurityMonitoringSignals", "connection_error", err)
 		return nil, err
@@ -89,14 +97,11 @@ func listSecurityMonitoringSignals(ctx context.Context, d *plugin.QueryData, _ *
 		opts.WithFilterTo(time.Now())
 	}
 
-	configuration.SetUnstableOperationEnabled("ListSecurityMonitoringSignals", true)
-	apiClient := datadog.NewAPIClient(configuration)
-
 	for {
-		resp, _, err := apiClient.SecurityMonitoringApi.ListSecurityMonitoringSignals(ctx, opts)
+		resp, httpResp, err := apiClient.SecurityMonitoringApi.ListSecurityMonitoringSignals(ctx, opts)
 		if err != nil {
 			plugin.Logger(ctx).Error("datadog_security_monitoring_signal.listSecurityMonitoringSignals", "query_error", err)
-			return nil, err
+			return nil, wrapAPIError(d, httpResp, err)
 		}
 
 		for _, securityMonitoringSignal := range resp.GetData() {
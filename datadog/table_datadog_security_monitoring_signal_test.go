@@ -0,0 +1,44 @@
+package datadog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListSecurityMonitoringSignals(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/security_monitoring/signals", nil, fakeResponse{Fixture: "v2/security_signals.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_security_monitoring_signal",
+		Columns: []string{"id", "title", "message", "tags", "filter_query"},
+		Quals:   stringQual("filter_query", "=", "status:high"),
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+
+	row := rows[0]
+	if row["title"] != "Brute force login" {
+		t.Errorf("title = %v, want Brute force login", row["title"])
+	}
+	if want := []interface{}{"source:auth", "env:prod"}; !reflect.DeepEqual(row["tags"], want) {
+		t.Errorf("tags = %v, want %v", row["tags"], want)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/security_monitoring/signals")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	query := requests[0].URL.Query()
+	if got := query.Get("filter[query]"); got != "status:high" {
+		t.Errorf("filter[query] = %q, want it pushed down", got)
+	}
+	if got := query.Get("page[limit]"); got != "10" {
+		t.Errorf("page[limit] = %q, want the limit pushed down", got)
+	}
+}
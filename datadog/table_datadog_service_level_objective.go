@@ -2,10 +2,6 @@ package datadog
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 
 	datadog "github.com/DataDog/datadog-api-client-go/api/v1/datadog"
@@ -14,21 +10,39 @@ import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// sloLink builds the akas and app_url of the slo rows.
+var sloLink = resourceLink{Resource: "slo", IDField: "Id", AppPath: "/slo?slo_id=%s"}
+
 func tableDatadogServiceLevelObjective(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_service_level_objective",
 		Description: "An SLO(Service Level Objective) provides a target percentage of a specific metric over a certain period of time.",
 		Get: &plugin.GetConfig{
 			Hydrate:    getSLO,
+			Tags:       apiFamilyTags("slo"),
 			KeyColumns: plugin.SingleColumn("id"),
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundError,
+			},
 		},
 		List: &plugin.ListConfig{
 			Hydrate: listSLOs,
+			Tags:    apiFamilyTags("slo"),
+		},
+		HydrateConfig: []plugin.HydrateConfig{
+			{
+				// The item may have been deleted since it was listed
+				Func: getSLO,
+				Tags: apiFamilyTags("slo"),
+				IgnoreConfig: &plugin.IgnoreConfig{
+					ShouldIgnoreErrorFunc: isNotFoundError,
+				},
+			},
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns(append([]*plugin.Column{
 			// Top columns
 			{Name: "name", Type: proto.ColumnType_STRING, Description: "Name of the SLO.", Transform: transform.FromField("Attributes.Name")},
-			{Name: "id", Type: proto.ColumnType_STRING, Transform: transform.FromGo(), Description: "ID of the SLO."},
+			{Name: "id", Type: proto.ColumnType_STRING, Transform: transform.FromField("Id"), Description: "ID of the SLO."},
 			{Name: "creator_email", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Creator.Email"), Description: "Email of the creator."},
 			{Name: "created_at", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("Attributes.CreatedAt").Transform(transform.NullIfZeroValue).Transform(convertDatetime), Description: "Timestamp of the SLO creation."},
 			{Name: "type", Type: proto.ColumnType_STRING, Description: "The type of the SLO. For more information about type, see https://docs.datadoghq.com/monitors/service_level_objectives/."},
@@ -45,7 +59,12 @@ func tableDatadogServiceLevelObjective(ctx context.Context) *plugin.Table {
 			{Name: "monitor_tags", Type: proto.ColumnType_JSON, Description: "If monitors that are associated with SLO have tags they will show here.", Transform: transform.FromField("Attributes.MonitorTags")},
 			{Name: "tags", Type: proto.ColumnType_JSON, Description: "Tags associated with SLO.", Transform: transform.FromField("Attributes.AllTags")},
 			{Name: "thresholds", Type: proto.ColumnType_JSON, Description: "Thresholds that are set for the SLOs.", Transform: transform.FromField("Attributes.Thresholds")},
-		},
+
+			// Steampipe standard columns
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Name"), Description: "Title of the resource."},
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, sloLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, sloLink), Description: "Link to the SLO in the Datadog app of the site."},
+		}, tagColumns("Attributes.AllTags", "team", "env", "service")...)),
 	}
 }
 
@@ -54,91 +73,42 @@ func tableDatadogServiceLevelObjective(ctx context.Context) *plugin.Table {
 // Therefore, we opted for a raw API call to list all the SLOs (as is done in the Datadog console). This approach also addresses the issue: https://github.com/turbot/steampipe-plugin-datadog/issues/63.
 
 func listSLOs(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
-	ctx, apiClient, err := connectV1(ctx, d)
+	client, err := connectRaw(ctx, d)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_service_level_objective.listSLOs", "connection_error", err)
 		return nil, err
 	}
 
-	steampipeConfig := GetConfig(d.Connection)
-
-	keys := ctx.Value(datadog.ContextAPIKeys).(map[string]datadog.APIKey)
-	apiKey := keys["apiKeyAuth"].Key
-	appKey := keys["appKeyAuth"].Key
-
-	pageNumber := 0
-	for {
-
-		params := url.Values{}
-		params.Add("query", "")
-		params.Add("sort", "")
-		params.Add("include_facets", "false")
-		params.Add("include_permissions", "true")
-		params.Add("page[size]", "1")
+	params := url.Values{}
+	params.Add("query", "")
+	params.Add("sort", "")
+	params.Add("include_facets", "false")
+	params.Add("include_permissions", "true")
 
-		params.Add("page[number]", fmt.Sprint(pageNumber))
-
-		fullUrl := fmt.Sprintf("%s?%s", *steampipeConfig.ApiURL+"api/v1/slo/search", params.Encode())
-
-		buildReq, err := http.NewRequest("GET", fullUrl, nil)
-		if err != nil {
-			plugin.Logger(ctx).Error("Error creating the request:", err)
-			return nil, err
-		}
-
-		// Set headers
-		buildReq.Header.Set("Content-Type", "application/json")
-		buildReq.Header.Set("Accept", "*/*")
-		buildReq.Header.Set("DD-API-KEY", apiKey)
-		buildReq.Header.Set("DD-APPLICATION-KEY", appKey)
-
-		response, err := SearchSLO(apiClient, buildReq)
-		if err != nil {
-			plugin.Logger(ctx).Error("datadog_service_level_objective.listSLOs", "api_error", err)
-			return nil, err
-		}
-
-		for _, slo := range response.Data.Attributes.SLOs {
+	err = listPageNumbers(ctx, client, "/api/v1/slo/search", params, 1, func(page *sloSearchResponse) (bool, error) {
+		for _, slo := range page.Data.Attributes.SLOs {
 			d.StreamListItem(ctx, slo.Data)
 
 			// Check if context has been cancelled or if the limit has been hit (if specified)
 			if d.RowsRemaining(ctx) == 0 {
-				return nil, nil
+				return false, nil
 			}
 		}
-
-		if *response.Meta.Pagination.LastNumber == pageNumber {
-			break
-		}
-		pageNumber++
-	}
-
-	return nil, nil
-}
-
-func SearchSLO(client *datadog.APIClient, req *http.Request) (*ApiResponse, error) {
-	resp, err := client.CallAPI(req)
+		return page.Meta.Pagination.hasNext(), nil
+	})
 	if err != nil {
-		return nil, err
+		plugin.Logger(ctx).Error("datadog_service_level_objective.listSLOs", "query_error", err)
+		return nil, wrapAPIError(d, nil, err)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	response := &ApiResponse{}
-	err = json.Unmarshal(body, response)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+	return nil, nil
 }
 
 func getSLO(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 
 	var sloID string
 	if h.Item != nil {
-		sloID = h.Item.(*SLOData).ID
+		sloID = h.Item.(*sloSearchResult).Id
 	} else {
 		sloID = d.EqualsQuals["id"].GetStringValue()
 	}
@@ -159,138 +129,56 @@ func getSLO(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (in
 		return nil, err
 	}
 
-	resp, _, err := apiClient.ServiceLevelObjectivesApi.GetSLO(ctx, sloID, opts)
+	resp, httpResp, err := apiClient.ServiceLevelObjectivesApi.GetSLO(ctx, sloID, opts)
 	if err != nil {
-		if err.Error() == "404 Not Found" {
-			return nil, nil
-		}
 		plugin.Logger(ctx).Error("datadog_service_level_objective.getSLO", "query_error", err)
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	return resp.GetData(), nil
 }
 
-// Root structure to represent the API response
-type ApiResponse struct {
-	Data  DataResponse  `json:"data"`
-	Meta  MetaResponse  `json:"meta"`
-	Links LinksResponse `json:"links"`
-}
-
-type DataResponse struct {
-	Type       string        `json:"type"`
-	Attributes SLOAttributes `json:"attributes"`
-}
-
-type SLOAttributes struct {
-	SLOs []SLOResponse `json:"slos"`
-}
-
-type SLOResponse struct {
-	Data *SLOData `json:"data"`
-}
-
-type SLOData struct {
-	Type       string                `json:"type"`
-	Attributes *SLOAttributesDetails `json:"attributes"`
-	ID         string                `json:"id"`
-}
-
-type SLOAttributesDetails struct {
-	Timeframe        *string         `json:"timeframe"`
-	WarningThreshold *float64        `json:"warning_threshold"`
-	Groups           interface{}     `json:"groups"`
-	Thresholds       []Threshold     `json:"thresholds"`
-	CreatedAt        *int64          `json:"created_at"`
-	Status           SLOStatus       `json:"status"`
-	TeamTags         []string        `json:"team_tags"`
-	ModifiedAt       *int64          `json:"modified_at"`
-	OverallStatus    []OverallStatus `json:"overall_status"`
-	AllTags          []string        `json:"all_tags"`
-	Query            *Query          `json:"query,omitempty"`
-	RequestContext   RequestContext  `json:"request_context"`
-	MonitorIDs       []int           `json:"monitor_ids,omitempty"`
-	MonitorTags      []int           `json:"monitor_tags,omitempty"`
-	ServiceTags      []string        `json:"service_tags"`
-	Name             *string         `json:"name"`
-	Description      interface{}     `json:"description"`
-	SLOType          *string         `json:"slo_type"`
-	TargetThreshold  *float64        `json:"target_threshold"`
-	Creator          Creator         `json:"creator"`
-	EnvTags          []string        `json:"env_tags"`
-}
-
-type Threshold struct {
-	TargetDisplay  *string     `json:"target_display"`
-	Timeframe      *string     `json:"timeframe"`
-	WarningDisplay interface{} `json:"warning_display"`
-	Warning        interface{} `json:"warning"`
-	Target         *float64    `json:"target"`
-}
-
-type SLOStatus struct {
-	CalculationError        interface{}     `json:"calculation_error"`
-	IndexedAt               *int64          `json:"indexed_at"`
-	SpanPrecision           *int            `json:"span_precision"`
-	RawErrorBudgetRemaining BudgetRemaining `json:"raw_error_budget_remaining"`
-	ErrorBudgetRemaining    *float64        `json:"error_budget_remaining"`
-	State                   *string         `json:"state"`
-	SLI                     *float64        `json:"sli"`
-}
-
-type BudgetRemaining struct {
-	Value float64 `json:"value"`
-	Unit  string  `json:"unit"`
-}
-
-type OverallStatus struct {
-	Status                  float64         `json:"status"`
-	Timeframe               string          `json:"timeframe"`
-	IndexedAt               int64           `json:"indexed_at"`
-	SpanPrecision           int             `json:"span_precision"`
-	RawErrorBudgetRemaining BudgetRemaining `json:"raw_error_budget_remaining"`
-	ErrorBudgetRemaining    float64         `json:"error_budget_remaining"`
-	Error                   interface{}     `json:"error"`
-	State                   string          `json:"state"`
-	Target                  float64         `json:"target"`
-}
-
-type Query struct {
-	Metrics     interface{} `json:"metrics"`
-	Denominator string      `json:"denominator"`
-	Numerator   string      `json:"numerator"`
-}
-
-type RequestContext struct {
-	UserHasWriteAccessForSLO bool `json:"user_has_write_access_for_slo"`
-}
-
-type Creator struct {
-	Name  string `json:"name"`
-	ID    int    `json:"id"`
-	Email string `json:"email"`
-}
-
-type MetaResponse struct {
-	Pagination *Pagination `json:"pagination"`
-}
-
-type Pagination struct {
-	Number      *int    `json:"number"`
-	FirstNumber *int    `json:"first_number"`
-	PrevNumber  *int    `json:"prev_number"`
-	NextNumber  *int    `json:"next_number"`
-	LastNumber  *int    `json:"last_number"`
-	Size        *int    `json:"size"`
-	Type        *string `json:"type"`
-	Total       *int    `json:"total"`
-}
-
-type LinksResponse struct {
-	Self  string `json:"self"`
-	Last  string `json:"last"`
-	Next  string `json:"next"`
-	Prev  string `json:"prev"`
-	First string `json:"first"`
+// sloSearchResponse is a page of the SLO search endpoint, which is not part of the
+// generated client. Only the attributes used by the table are decoded.
+type sloSearchResponse struct {
+	Data struct {
+		Attributes struct {
+			SLOs []struct {
+				Data *sloSearchResult `json:"data"`
+			} `json:"slos"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination *pageNumberPagination `json:"pagination"`
+	} `json:"meta"`
+}
+
+// sloSearchResult names its ID field like the SLO model of the client, so the rows
+// of the list and get calls share it.
+type sloSearchResult struct {
+	Id         string                     `json:"id"`
+	Type       string                     `json:"type"`
+	Attributes *sloSearchResultAttributes `json:"attributes"`
+}
+
+type sloSearchResultAttributes struct {
+	Name        *string     `json:"name"`
+	Description interface{} `json:"description"`
+	SLOType     *string     `json:"slo_type"`
+	CreatedAt   *int64      `json:"created_at"`
+	ModifiedAt  *int64      `json:"modified_at"`
+	Creator     struct {
+		Name  string `json:"name"`
+		ID    int    `json:"id"`
+		Email string `json:"email"`
+	} `json:"creator"`
+	Groups      interface{} `json:"groups"`
+	MonitorIDs  []int       `json:"monitor_ids,omitempty"`
+	MonitorTags []string    `json:"monitor_tags,omitempty"`
+	Query       interface{} `json:"query,omitempty"`
+	AllTags     []string    `json:"all_tags"`
+	TeamTags    []string    `json:"team_tags"`
+	EnvTags     []string    `json:"env_tags"`
+	ServiceTags []string    `json:"service_tags"`
+	Thresholds  interface{} `json:"thresholds"`
 }
@@ -0,0 +1,71 @@
+package datadog
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestListSLOsPagination(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/slo/search", url.Values{"page[number]": {"0"}}, fakeResponse{Fixture: "v1/slo_search_page0.json"})
+	f.handle("GET", "/api/v1/slo/search", url.Values{"page[number]": {"1"}}, fakeResponse{Fixture: "v1/slo_search_page1.json"})
+	f.handle("GET", "/api/v1/slo/slo-1", nil, fakeResponse{Fixture: "v1/slo.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_service_level_objective",
+		Columns: []string{"id", "name", "creator_email", "created_at", "tags", "monitor_ids", "akas"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "id")
+
+	if got, want := columnValues(rows, "name"), []interface{}{"API availability", "Checkout latency"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("name = %v, want %v", got, want)
+	}
+	if want := time.Unix(1650000000, 0).UTC(); rows[0]["created_at"] != want {
+		t.Errorf("created_at = %v, want %v", rows[0]["created_at"], want)
+	}
+	if rows[1]["creator_email"] != "bob@example.com" {
+		t.Errorf("creator_email = %v", rows[1]["creator_email"])
+	}
+	if want := []interface{}{float64(1001)}; !reflect.DeepEqual(rows[1]["monitor_ids"], want) {
+		t.Errorf("monitor_ids = %v, want %v", rows[1]["monitor_ids"], want)
+	}
+	if want := []interface{}{"datadog://" + f.server.Listener.Addr().String() + "/slo/" + rows[0]["id"].(string)}; !reflect.DeepEqual(rows[0]["akas"], want) {
+		t.Errorf("akas = %v, want %v", rows[0]["akas"], want)
+	}
+
+	requests := f.recorder.requestsTo("/api/v1/slo/search")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := requests[0].Header.Get("DD-APPLICATION-KEY"); got != "test-app-key" {
+		t.Errorf("DD-APPLICATION-KEY = %q, want test-app-key", got)
+	}
+}
+
+func TestGetSLO(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v1/slo/slo-1", nil, fakeResponse{Fixture: "v1/slo.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_service_level_objective",
+		Columns: []string{"id", "name", "configured_alert_ids", "akas"},
+		Quals:   stringQual("id", "=", "slo-1"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(rows))
+	}
+	if want := []interface{}{float64(2001), float64(2002)}; !reflect.DeepEqual(rows[0]["configured_alert_ids"], want) {
+		t.Errorf("configured_alert_ids = %v, want %v", rows[0]["configured_alert_ids"], want)
+	}
+	if want := []interface{}{"datadog://" + f.server.Listener.Addr().String() + "/slo/slo-1"}; !reflect.DeepEqual(rows[0]["akas"], want) {
+		t.Errorf("akas = %v, want %v", rows[0]["akas"], want)
+	}
+}
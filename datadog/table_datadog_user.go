@@ -10,28 +10,36 @@ import (
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+// userLink builds the akas and app_url of the user rows.
+var userLink = resourceLink{Resource: "user", IDField: "Id", AppPath: "/organization-settings/users?user_id=%s"}
+
 func tableDatadogUser(ctx context.Context) *plugin.Table {
 	return &plugin.Table{
 		Name:        "datadog_user",
 		Description: "A user belongs to an organization and can be assigned roles.",
 		Get: &plugin.GetConfig{
 			Hydrate:    getUser,
+			Tags:       apiFamilyTags("users"),
 			KeyColumns: plugin.SingleColumn("id"),
+			IgnoreConfig: &plugin.IgnoreConfig{
+				ShouldIgnoreErrorFunc: isNotFoundError,
+			},
 		},
 		List: &plugin.ListConfig{
 			Hydrate: listUsers,
+			Tags:    map[string]string{"api_family": "users", "pagination": "concurrent"},
 			KeyColumns: plugin.KeyColumnSlice{
 				{Name: "status", Require: plugin.Optional},
 			},
 		},
-		Columns: []*plugin.Column{
+		Columns: commonColumns([]*plugin.Column{
 			// Top columns
 			{Name: "email", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Email"), Description: "Email of the user."},
 			{Name: "id", Type: proto.ColumnType_STRING, Description: "Id of the user."},
 			{Name: "name", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Name").Transform(valueFromNullable), Description: "Name of the user."},
 			{Name: "handle", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Handle"), Description: "Handle of the user."},
 			{Name: "created_at", Type: proto.ColumnType_TIMESTAMP, Transform: transform.FromField("Attributes.CreatedAt"), Description: "Creation time of the user."},
-			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Title").Transform(valueFromNullable), Description: "Title of the user."},
+			{Name: "title", Type: proto.ColumnType_STRING, Transform: transform.FromField("Attributes.Title").Transform(valueFromNullable), Description: "Job title of the user. Unlike in other tables, this is not the Steampipe standard title column."},
 
 			// Other useful columns
 			{Name: "disabled", Type: proto.ColumnType_BOOL, Transform: transform.FromField("Attributes.Disabled"), Description: "Indicates if the user is disabled."},
@@ -44,7 +52,11 @@ func tableDatadogUser(ctx context.Context) *plugin.Table {
 			// JSON columns
 			{Name: "role_ids", Type: proto.ColumnType_JSON, Transform: transform.FromField("Relationships.Roles.Data").Transform(roleList), Description: "A list of role IDs attached to user."},
 			{Name: "relationships", Type: proto.ColumnType_JSON, Description: "Relationships of the user object returned by the API."},
-		},
+
+			// Steampipe standard columns
+			{Name: "akas", Type: proto.ColumnType_JSON, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAkas, userLink), Description: "Array of globally unique identifier strings (also known as) for the resource."},
+			{Name: "app_url", Type: proto.ColumnType_STRING, Hydrate: getSite, Transform: transform.FromValue().TransformP(resourceAppURL, userLink), Description: "Link to the user in the Datadog app of the site."},
+		}),
 	}
 }
 
@@ -68,36 +80,40 @@ func listUsers(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData)
 		opts.WithFilterStatus(filterStatus)
 	}
 
-	paging := true
-	count := int64(0)
-
-	for paging {
-		resp, _, err := apiClient.UsersApi.ListUsers(ctx, opts)
-		if err != nil {
-			plugin.Logger(ctx).Error("datadog_user.listUsers", "query_error", err)
-			return nil, err
-		}
+	resp, httpResp, err := apiClient.UsersApi.ListUsers(ctx, opts)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_user.listUsers", "query_error", err)
+		return nil, wrapAPIError(d, httpResp, err)
+	}
 
+	streamPage := func(resp datadog.UsersResponse) bool {
 		for _, user := range resp.GetData() {
-			count++
 			d.StreamListItem(ctx, user)
 			// Check if context has been cancelled or if the limit has been hit (if specified)
 			if d.RowsRemaining(ctx) == 0 {
-				return nil, nil
+				return false
 			}
 		}
+		return true
+	}
+	if !streamPage(resp) {
+		return nil, nil
+	}
 
-		// Break loop if using filter
-		if resp.Meta.Page.HasTotalFilteredCount() {
-			if count >= resp.Meta.Page.GetTotalFilteredCount() {
-				return nil, nil
-			}
-		}
-		// Break loop if not using filter
-		if count >= resp.Meta.Page.GetTotalCount() {
-			return nil, nil
-		}
-		opts.WithPageNumber(*opts.PageNumber + 1)
+	// The first page tells how many users there are, so fetch the other pages concurrently
+	total := resp.Meta.Page.GetTotalCount()
+	if resp.Meta.Page.HasTotalFilteredCount() {
+		total = resp.Meta.Page.GetTotalFilteredCount()
+	}
+	err = listPagesConcurrently(ctx, d, 1, lastPageNumber(total, *opts.PageSize), func(ctx context.Context, page int64) (datadog.UsersResponse, error) {
+		pageOpts := opts
+		pageOpts.PageNumber = datadog.PtrInt64(page)
+		resp, httpResp, err := apiClient.UsersApi.ListUsers(ctx, pageOpts)
+		return resp, wrapAPIError(d, httpResp, err)
+	}, streamPage)
+	if err != nil {
+		plugin.Logger(ctx).Error("datadog_user.listUsers", "query_error", err)
+		return nil, wrapAPIError(d, nil, err)
 	}
 
 	return nil, nil
@@ -123,13 +139,10 @@ func getUser(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (i
 	}
 
 	// https: //github.com/DataDog/datadog-api-client-go/blob/master/api/v2/datadog/docs/UsersApi.md#GetUser
-	resp, _, err := apiClient.UsersApi.GetUser(ctx, userID)
+	resp, httpResp, err := apiClient.UsersApi.GetUser(ctx, userID)
 	if err != nil {
 		plugin.Logger(ctx).Error("datadog_role.getUser", "query_error", err)
-		if err.Error() == "404 Not Found" {
-			return nil, nil
-		}
-		return nil, err
+		return nil, wrapAPIError(d, httpResp, err)
 	}
 
 	return resp.GetData(), nil
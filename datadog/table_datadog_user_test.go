@@ -0,0 +1,79 @@
+package datadog
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestListUsersPagination(t *testing.T) {
+	f := newFakeDatadog(t)
+	// The pages claim 150 users, so the page after the first is requested too
+	f.handle("GET", "/api/v2/users", url.Values{"page[number]": {"0"}}, fakeResponse{Fixture: "v2/users_page0.json"})
+	f.handle("GET", "/api/v2/users", url.Values{"page[number]": {"1"}}, fakeResponse{Fixture: "v2/users_page1.json"})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_user",
+		Columns: []string{"id", "email", "name", "title", "service_account", "role_ids"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows = sortRows(rows, "email")
+
+	if got, want := columnValues(rows, "email"), []interface{}{"alice@example.com", "bot@example.com", "carol@example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("email = %v, want %v", got, want)
+	}
+	if got, want := columnValues(rows, "name"), []interface{}{"Alice", nil, "Carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("name = %v, want %v", got, want)
+	}
+	if got, want := rows[0]["role_ids"], []interface{}{"role-admin", "role-ro"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("role_ids = %v, want %v", got, want)
+	}
+	if rows[1]["service_account"] != true {
+		t.Errorf("service_account = %v, want true", rows[1]["service_account"])
+	}
+
+	if got := len(f.recorder.requestsTo("/api/v2/users")); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
+
+func TestListUsersStatusQual(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/users", nil, fakeResponse{Fixture: "v2/users_page1.json"})
+
+	_, err := f.query(testQuery{
+		Table:   "datadog_user",
+		Columns: []string{"id", "status"},
+		Quals:   stringQual("status", "=", "Active"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	requests := f.recorder.requestsTo("/api/v2/users")
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := requests[0].URL.Query().Get("filter[status]"); got != "Active" {
+		t.Errorf("filter[status] = %q, want it pushed down", got)
+	}
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	f := newFakeDatadog(t)
+	f.handle("GET", "/api/v2/users/missing", nil, fakeResponse{Status: 404, Body: `{"errors": ["User not found"]}`})
+
+	rows, err := f.query(testQuery{
+		Table:   "datadog_user",
+		Columns: []string{"id", "email"},
+		Quals:   stringQual("id", "=", "missing"),
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("got %d rows, want 0", len(rows))
+	}
+}
@@ -0,0 +1,128 @@
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// tagColumns returns the tags_map column and a column per tag key, e.g. team, parsed
+// from the key:value tags in the field of the item.
+func tagColumns(field string, keys ...string) []*plugin.Column {
+	columns := []*plugin.Column{
+		{Name: "tags_map", Type: proto.ColumnType_JSON, Transform: transform.FromField(field).Transform(tagsToMap), Description: "The tags as an object of tag keys to the list of their values, e.g. {\"team\": [\"payments\"]}. Tags without a value have an empty list."},
+	}
+	for _, key := range keys {
+		columns = append(columns, &plugin.Column{
+			Name:        key,
+			Type:        proto.ColumnType_STRING,
+			Transform:   transform.FromField(field).TransformP(tagValue, key),
+			Description: fmt.Sprintf("The value of the %s tag. The first value is used if the tag has several.", key),
+		})
+	}
+	return columns
+}
+
+//// TRANSFORM FUNCTIONS
+
+// tagsToMap turns Datadog tags in the form key:value into an object of tag keys to the
+// list of their values.
+func tagsToMap(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	tags, ok := tagList(d.Value)
+	if !ok {
+		return nil, nil
+	}
+	return parseTags(tags), nil
+}
+
+// tagValue returns the first value of the tag key given as the transform param.
+func tagValue(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	tags, ok := tagList(d.Value)
+	if !ok {
+		return nil, nil
+	}
+	values := parseTags(tags)[d.Param.(string)]
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values[0], nil
+}
+
+// parseTags splits tags at the first colon into their key and value. Values of a key
+// keep the order of the tags, without duplicates.
+func parseTags(tags []string) map[string][]string {
+	tagsMap := map[string][]string{}
+	for _, tag := range tags {
+		key, value, hasValue := strings.Cut(tag, ":")
+		if key == "" {
+			continue
+		}
+		values, seen := tagsMap[key]
+		if !seen {
+			values = []string{}
+		}
+		if hasValue && !containsString(values, value) {
+			values = append(values, value)
+		}
+		tagsMap[key] = values
+	}
+	return tagsMap
+}
+
+// tagList returns the tags of the tag fields of the API models, which are lists of
+// tags or, for hosts, lists of tags per source.
+func tagList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case *[]string:
+		if v == nil {
+			return nil, false
+		}
+		return *v, true
+	case []interface{}:
+		tags := make([]string, 0, len(v))
+		for _, tag := range v {
+			if s, ok := tag.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+		return tags, true
+	case *[]interface{}:
+		if v == nil {
+			return nil, false
+		}
+		return tagList(*v)
+	case map[string][]string:
+		sources := make([]string, 0, len(v))
+		for source := range v {
+			sources = append(sources, source)
+		}
+		sort.Strings(sources)
+		tags := []string{}
+		for _, source := range sources {
+			tags = append(tags, v[source]...)
+		}
+		return tags, true
+	case *map[string][]string:
+		if v == nil {
+			return nil, false
+		}
+		return tagList(*v)
+	}
+	return nil, false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
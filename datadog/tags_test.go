@@ -0,0 +1,43 @@
+package datadog
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	got := parseTags([]string{"team:web", "env:prod", "team:web", "team:payments", "url:https://example.com", "critical", ":orphan"})
+	want := map[string][]string{
+		"team":     {"web", "payments"},
+		"env":      {"prod"},
+		"url":      {"https://example.com"},
+		"critical": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTags = %v, want %v", got, want)
+	}
+}
+
+func TestTagList(t *testing.T) {
+	tags := []string{"team:web"}
+	bySource := map[string][]string{"Users": {"team:web"}, "Datadog": {"host:web-1"}}
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+		ok    bool
+	}{
+		{"strings", []string{"env:prod"}, []string{"env:prod"}, true},
+		{"pointer to strings", &tags, []string{"team:web"}, true},
+		{"nil pointer", (*[]string)(nil), nil, false},
+		{"interfaces", []interface{}{"env:prod", 1}, []string{"env:prod"}, true},
+		{"tags by source", &bySource, []string{"host:web-1", "team:web"}, true},
+		{"other", "env:prod", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := tagList(tt.value)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: tagList = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
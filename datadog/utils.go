@@ -2,173 +2,428 @@ package datadog
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	datadogV1 "github.com/DataDog/datadog-api-client-go/api/v1/datadog"
 	datadogV2 "github.com/DataDog/datadog-api-client-go/api/v2/datadog"
 
 	"github.com/pkg/errors"
+	connectionmanager "github.com/turbot/steampipe-plugin-sdk/v5/connection"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
 )
 
+const (
+	cacheKeyConnectionSettings = "datadog_connection_settings"
+	cacheKeyClientV1           = "datadog_client_v1"
+	cacheKeyClientV2           = "datadog_client_v2"
+	cacheKeyHTTPClient         = "datadog_http_client"
+	cacheKeyConnectionInfo     = "datadog_connection_info"
+	cacheKeyLogSearchSlots     = "datadog_log_search_slots"
+)
+
+// clientCacheMutex serializes building of the cached clients, so concurrent
+// hydrate calls of a connection share a single client per API version.
+var clientCacheMutex sync.Mutex
+
+// connectionSettings holds the resolved authentication and endpoint settings of a connection.
+type connectionSettings struct {
+	authType    string
+	apiKey      string
+	appKey      string
+	accessToken string
+	apiURL      *url.URL
+	site        string
+}
+
+// apiBasePath returns the path of the api_url without its trailing slash, which the
+// paths of the API are appended to, e.g. "" for "https://api.datadoghq.com/".
+func (s *connectionSettings) apiBasePath() string {
+	return strings.TrimSuffix(s.apiURL.Path, "/")
+}
+
 func connectV1(ctx context.Context, d *plugin.QueryData) (context.Context, *datadogV1.APIClient, error) {
+	ctx = withQueryStats(ctx, d)
 
-	// Default to the env var settings
-	apiKey := os.Getenv("DD_CLIENT_API_KEY")
-	appKey := os.Getenv("DD_CLIENT_APP_KEY")
-	apiURL := os.Getenv("DD_CLIENT_API_URL")
-	if apiURL == "" {
-		apiURL = "https://api.datadoghq.com/"
+	settings, err := getConnectionSettings(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		return ctx, nil, err
 	}
 
-	// Prefer config settings
-	config := GetConfig(d.Connection)
+	// Fail fast with a clear message if the keys are rejected by Datadog
+	if _, err := getConnectionInfo(ctx, d.Connection, d.ConnectionCache); err != nil {
+		return ctx, nil, err
+	}
+
+	apiClient, err := getClientV1(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	return contextV1(ctx, settings), apiClient, nil
+}
+
+func connectV2(ctx context.Context, d *plugin.QueryData) (context.Context, *datadogV2.APIClient, *datadogV2.Configuration, error) {
+	ctx = withQueryStats(ctx, d)
 
-	if config.APIKey != nil {
-		apiKey = *config.APIKey
+	settings, err := getConnectionSettings(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		return ctx, nil, nil, err
 	}
-	if config.AppKey != nil {
-		appKey = *config.AppKey
+
+	// Fail fast with a clear message if the keys are rejected by Datadog
+	if _, err := getConnectionInfo(ctx, d.Connection, d.ConnectionCache); err != nil {
+		return ctx, nil, nil, err
 	}
-	if config.ApiURL != nil {
-		apiURL = *config.ApiURL
+
+	apiClient, err := getClientV2(ctx, d.Connection, d.ConnectionCache)
+	if err != nil {
+		return ctx, nil, nil, err
 	}
 
-	// Error if the minimum config is not set
-	if apiKey == "" {
-		return ctx, nil, errors.New("api_key must be configured")
+	return contextV2(ctx, settings), apiClient, apiClient.GetConfig(), nil
+}
+
+// contextV1 adds the authentication and server settings used by the V1 client to the context.
+func contextV1(ctx context.Context, settings *connectionSettings) context.Context {
+	if settings.authType == authTypeBearer {
+		ctx = context.WithValue(ctx, datadogV1.ContextAccessToken, settings.accessToken)
+	} else {
+		ctx = context.WithValue(ctx, datadogV1.ContextAPIKeys,
+			map[string]datadogV1.APIKey{
+				"apiKeyAuth": {Key: settings.apiKey},
+				"appKeyAuth": {Key: settings.appKey},
+			},
+		)
 	}
 
-	if appKey == "" {
-		return ctx, nil, errors.New("app_key must be configured")
+	// Use the api name and protocol on ServerIndex{1}. The name keeps the path of the
+	// api_url, e.g. of a proxy serving the API below "/datadog/".
+	ctx = context.WithValue(ctx, datadogV1.ContextServerIndex, 1)
+	ctx = context.WithValue(ctx,
+		datadogV1.ContextServerVariables,
+		map[string]string{
+			"name":     settings.apiURL.Host + settings.apiBasePath(),
+			"protocol": settings.apiURL.Scheme,
+		})
+
+	return ctx
+}
+
+// contextV2 adds the authentication and server settings used by the V2 client to the context.
+func contextV2(ctx context.Context, settings *connectionSettings) context.Context {
+	if settings.authType == authTypeBearer {
+		ctx = context.WithValue(ctx, datadogV2.ContextAccessToken, settings.accessToken)
+	} else {
+		ctx = context.WithValue(ctx, datadogV2.ContextAPIKeys,
+			map[string]datadogV2.APIKey{
+				"apiKeyAuth": {Key: settings.apiKey},
+				"appKeyAuth": {Key: settings.appKey},
+			},
+		)
 	}
 
-	ctx = context.WithValue(ctx, datadogV1.ContextAPIKeys,
-		map[string]datadogV1.APIKey{
-			"apiKeyAuth": {Key: apiKey},
-			"appKeyAuth": {Key: appKey},
-		},
-	)
+	// Use the api name and protocol on ServerIndex{1}. The name keeps the path of the
+	// api_url, e.g. of a proxy serving the API below "/datadog/".
+	ctx = context.WithValue(ctx, datadogV2.ContextServerIndex, 1)
+	ctx = context.WithValue(ctx,
+		datadogV2.ContextServerVariables,
+		map[string]string{
+			"name":     settings.apiURL.Host + settings.apiBasePath(),
+			"protocol": settings.apiURL.Scheme,
+		})
 
-	if apiURL != "" {
-		parsedAPIURL, parseErr := url.Parse(apiURL)
-		if parseErr != nil {
-			return ctx, nil, fmt.Errorf(`invalid API URL : %v`, parseErr)
-		}
-		if parsedAPIURL.Host == "" || parsedAPIURL.Scheme == "" {
-			return ctx, nil, fmt.Errorf(`missing protocol or host : %v`, apiURL)
-		}
+	return ctx
+}
+
+// getClientV1 returns the V1 API client of the connection.
+func getClientV1(ctx context.Context, connection *plugin.Connection, cache *connectionmanager.ConnectionCache) (*datadogV1.APIClient, error) {
+	clientCacheMutex.Lock()
+	defer clientCacheMutex.Unlock()
 
-		strings.Split(parsedAPIURL.Host, "/")
-		// If api url is passed, set and use the api name and protocol on ServerIndex{1}
-		ctx = context.WithValue(ctx, datadogV1.ContextServerIndex, 1)
-		ctx = context.WithValue(ctx,
-			datadogV1.ContextServerVariables,
-			map[string]string{
-				"name":     parsedAPIURL.Host,
-				"protocol": parsedAPIURL.Scheme,
-			})
+	if cachedData, ok := cache.Get(ctx, cacheKeyClientV1); ok {
+		return cachedData.(*datadogV1.APIClient), nil
 	}
 
-	// Modify default client for retry handling
-	httpClientV1 := http.DefaultClient
-	ctOptions := CustomTransportOptions{}
-	timeout := time.Duration(int64(60)) * time.Second
-	ctOptions.Timeout = &timeout
-	httpClientV1.Transport = NewCustomTransport(httpClientV1.Transport, ctOptions)
+	httpClient, err := getHTTPClient(ctx, connection, cache)
+	if err != nil {
+		return nil, err
+	}
 
 	configuration := datadogV1.NewConfiguration()
-	configuration.HTTPClient = httpClientV1
+	configuration.HTTPClient = httpClient
 	configuration.UserAgent = "Steampipe"
 	apiClient := datadogV1.NewAPIClient(configuration)
 
-	return ctx, apiClient, nil
+	// Save to cache
+	_ = cache.Set(ctx, cacheKeyClientV1, apiClient)
+
+	return apiClient, nil
 }
 
-func connectV2(ctx context.Context, d *plugin.QueryData) (context.Context, *datadogV2.APIClient, *datadogV2.Configuration, error) {
+// getClientV2 returns the V2 API client of the connection.
+func getClientV2(ctx context.Context, connection *plugin.Connection, cache *connectionmanager.ConnectionCache) (*datadogV2.APIClient, error) {
+	clientCacheMutex.Lock()
+	defer clientCacheMutex.Unlock()
 
-	// Default to the env var settings
-	apiKey := os.Getenv("DD_CLIENT_API_KEY")
-	appKey := os.Getenv("DD_CLIENT_APP_KEY")
-	apiURL := os.Getenv("DD_CLIENT_API_URL")
-	if apiURL == "" {
-		apiURL = "https://api.datadoghq.com/"
+	if cachedData, ok := cache.Get(ctx, cacheKeyClientV2); ok {
+		return cachedData.(*datadogV2.APIClient), nil
 	}
 
-	// Prefer config settings
-	config := GetConfig(d.Connection)
-
-	if config.APIKey != nil {
-		apiKey = *config.APIKey
+	httpClient, err := getHTTPClient(ctx, connection, cache)
+	if err != nil {
+		return nil, err
 	}
-	if config.AppKey != nil {
-		appKey = *config.AppKey
+
+	configuration := datadogV2.NewConfiguration()
+	configuration.HTTPClient = httpClient
+	configuration.UserAgent = "Steampipe"
+	// The client is shared by all tables, so enable the unstable operations used by them upfront
+	configuration.SetUnstableOperationEnabled("ListSecurityMonitoringSignals", true)
+	apiClient := datadogV2.NewAPIClient(configuration)
+
+	// Save to cache
+	_ = cache.Set(ctx, cacheKeyClientV2, apiClient)
+
+	return apiClient, nil
+}
+
+// getHTTPClient returns the HTTP client of the connection, which is shared by
+// the V1 and V2 API clients and owns the transport chain used for retry handling.
+// The caller must hold clientCacheMutex.
+func getHTTPClient(ctx context.Context, connection *plugin.Connection, cache *connectionmanager.ConnectionCache) (*http.Client, error) {
+	if cachedData, ok := cache.Get(ctx, cacheKeyHTTPClient); ok {
+		return cachedData.(*http.Client), nil
 	}
-	if config.ApiURL != nil {
-		apiURL = *config.ApiURL
+
+	// Each connection gets its own transport, so connection pools and settings
+	// are never shared with other connections or with http.DefaultClient
+	baseTransport, err := newBaseTransport(connection)
+	if err != nil {
+		return nil, err
 	}
 
-	// Error if the minimum config is not set
-	if apiKey == "" {
-		return ctx, nil, nil, errors.New("api_key must be configured")
+	ctOptions := getCustomTransportOptions(connection)
+	familyLimiters, err := newFamilyLimiters(GetConfig(connection).RateLimits)
+	if err != nil {
+		return nil, err
+	}
+	ctOptions.FamilyLimiters = familyLimiters
+	httpClient := &http.Client{
+		Transport: NewCustomTransport(baseTransport, ctOptions),
 	}
 
-	if appKey == "" {
-		return ctx, nil, nil, errors.New("app_key must be configured")
+	// Save to cache
+	_ = cache.Set(ctx, cacheKeyHTTPClient, httpClient)
+
+	return httpClient, nil
+}
+
+// newBaseTransport builds the transport of the connection from the proxy and TLS
+// settings in the connection config.
+func newBaseTransport(connection *plugin.Connection) (*http.Transport, error) {
+	config := GetConfig(connection)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.ProxyURL != nil && *config.ProxyURL != "" {
+		proxyURL, err := url.Parse(*config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy_url: %v", err)
+		}
+		if proxyURL.Host == "" || proxyURL.Scheme == "" {
+			return nil, fmt.Errorf("proxy_url is missing protocol or host: %s", *config.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
 	}
 
-	ctx = context.WithValue(ctx, datadogV2.ContextAPIKeys,
-		map[string]datadogV2.APIKey{
-			"apiKeyAuth": {Key: apiKey},
-			"appKeyAuth": {Key: appKey},
-		},
-	)
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
 
-	ctx = context.WithValue(
-		ctx,
-		datadogV2.ContextServerVariables,
-		map[string]string{"basePath": "v2"},
-	)
+	if config.CACertificateFile != nil && *config.CACertificateFile != "" {
+		caCert, err := os.ReadFile(*config.CACertificateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_certificate_file: %v", err)
+		}
+		// Trust the custom CA in addition to the system ones
+		certPool, err := x509.SystemCertPool()
+		if err != nil || certPool == nil {
+			certPool = x509.NewCertPool()
+		}
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in ca_certificate_file: %s", *config.CACertificateFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
 
-	if apiURL != "" {
-		parsedAPIURL, parseErr := url.Parse(apiURL)
-		if parseErr != nil {
-			return ctx, nil, nil, fmt.Errorf(`invalid API URL : %v`, parseErr)
+	certFile := config.ClientCertificateFile
+	keyFile := config.ClientKeyFile
+	if (certFile != nil && *certFile != "") || (keyFile != nil && *keyFile != "") {
+		if certFile == nil || *certFile == "" || keyFile == nil || *keyFile == "" {
+			return nil, errors.New("client_certificate_file and client_key_file must be configured together")
 		}
-		if parsedAPIURL.Host == "" || parsedAPIURL.Scheme == "" {
-			return ctx, nil, nil, fmt.Errorf(`missing protocol or host : %v`, apiURL)
+		clientCert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
 		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
 
-		strings.Split(parsedAPIURL.Host, "/")
-		// If api url is passed, set and use the api name and protocol on ServerIndex{1}
-		ctx = context.WithValue(ctx, datadogV2.ContextServerIndex, 1)
-		ctx = context.WithValue(ctx,
-			datadogV2.ContextServerVariables,
-			map[string]string{
-				"name":     parsedAPIURL.Host,
-				"protocol": parsedAPIURL.Scheme,
-			})
+	if config.InsecureSkipVerify != nil && *config.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
 	}
 
-	// Modify default client for retry handling
-	httpClientV2 := http.DefaultClient
+	transport.TLSClientConfig = tlsConfig
+
+	return transport, nil
+}
+
+// getCustomTransportOptions builds the retry and backoff options of the
+// transport from the connection config. Invalid values fall back to the defaults.
+func getCustomTransportOptions(connection *plugin.Connection) CustomTransportOptions {
+	config := GetConfig(connection)
 	ctOptions := CustomTransportOptions{}
-	timeout := time.Duration(int64(60)) * time.Second
-	ctOptions.Timeout = &timeout
-	httpClientV2.Transport = NewCustomTransport(httpClientV2.Transport, ctOptions)
 
-	configuration := datadogV2.NewConfiguration()
-	configuration.HTTPClient = httpClientV2
-	configuration.UserAgent = "Steampipe"
-	apiClient := datadogV2.NewAPIClient(configuration)
+	if config.MaxErrorRetryAttempts != nil && *config.MaxErrorRetryAttempts >= 0 {
+		ctOptions.MaxRetries = config.MaxErrorRetryAttempts
+	}
+	if config.MinErrorRetryDelay != nil && *config.MinErrorRetryDelay > 0 {
+		minBackoff := time.Duration(*config.MinErrorRetryDelay) * time.Millisecond
+		ctOptions.MinBackoff = &minBackoff
+	}
+	if config.MaxErrorRetryDelay != nil && *config.MaxErrorRetryDelay > 0 {
+		maxBackoff := time.Duration(*config.MaxErrorRetryDelay) * time.Millisecond
+		ctOptions.MaxBackoff = &maxBackoff
+	}
+	if config.ErrorRetryTimeout != nil && *config.ErrorRetryTimeout > 0 {
+		timeout := time.Duration(*config.ErrorRetryTimeout) * time.Second
+		ctOptions.Timeout = &timeout
+	}
+
+	return ctOptions
+}
+
+// getConnectionSettings resolves the keys and API URL of the connection and
+// caches them, so they are resolved only once per connection.
+func getConnectionSettings(ctx context.Context, connection *plugin.Connection, cache *connectionmanager.ConnectionCache) (*connectionSettings, error) {
+	if cachedData, ok := cache.Get(ctx, cacheKeyConnectionSettings); ok {
+		return cachedData.(*connectionSettings), nil
+	}
+
+	if err := validateConfig(connection); err != nil {
+		return nil, err
+	}
+
+	config := GetConfig(connection)
+	settings := &connectionSettings{
+		authType: authTypeAPIKey,
+	}
+	if config.AuthType != nil && *config.AuthType != "" {
+		settings.authType = strings.ToLower(*config.AuthType)
+	}
+
+	switch settings.authType {
+	case authTypeAPIKey:
+		apiKey, appKey, err := resolveCredentials(ctx, connection)
+		if err != nil {
+			return nil, err
+		}
+
+		// Error if the minimum config is not set
+		if apiKey == "" {
+			return nil, errors.New("api_key must be configured")
+		}
+
+		if appKey == "" {
+			return nil, errors.New("app_key must be configured")
+		}
+		settings.apiKey = apiKey
+		settings.appKey = appKey
+	case authTypeBearer:
+		accessToken, _ := firstEnv("DD_ACCESS_TOKEN")
+		if config.AccessToken != nil && *config.AccessToken != "" {
+			accessToken = *config.AccessToken
+		}
+		if accessToken == "" {
+			return nil, errors.New("access_token must be configured when auth_type is \"bearer\"")
+		}
+		settings.accessToken = accessToken
+	default:
+		return nil, fmt.Errorf("invalid auth_type %q, must be one of: %s, %s", settings.authType, authTypeAPIKey, authTypeBearer)
+	}
+
+	apiURL, err := getAPIURL(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.apiURL = apiURL
+	settings.site = siteName(apiURL)
+
+	// Save to cache
+	_ = cache.Set(ctx, cacheKeyConnectionSettings, settings)
+
+	return settings, nil
+}
+
+// setAuthHeaders authenticates a request built outside of the generated clients.
+func setAuthHeaders(req *http.Request, settings *connectionSettings) {
+	if settings.authType == authTypeBearer {
+		req.Header.Set("Authorization", "Bearer "+settings.accessToken)
+		return
+	}
+	req.Header.Set("DD-API-KEY", settings.apiKey)
+	req.Header.Set("DD-APPLICATION-KEY", settings.appKey)
+}
+
+// getAPIURL resolves the base API URL of the connection in below order:
+//  1. The "api_url" specified in the config
+//  2. The "site" specified in the config
+//  3. The `DD_CLIENT_API_URL` environment variable
+//  4. The `DD_SITE` environment variable
+//  5. The default "us1" site, i.e. "https://api.datadoghq.com/"
+func getAPIURL(connection *plugin.Connection) (*url.URL, error) {
+	config := GetConfig(connection)
+
+	var apiURL, site string
+	switch {
+	case config.ApiURL != nil && *config.ApiURL != "":
+		apiURL = *config.ApiURL
+	case config.Site != nil && *config.Site != "":
+		site = *config.Site
+	case os.Getenv("DD_CLIENT_API_URL") != "":
+		apiURL = os.Getenv("DD_CLIENT_API_URL")
+	case os.Getenv("DD_SITE") != "":
+		site = os.Getenv("DD_SITE")
+	default:
+		site = defaultSite
+	}
+
+	if apiURL == "" {
+		domain, err := siteDomain(site)
+		if err != nil {
+			return nil, err
+		}
+		apiURL = fmt.Sprintf("https://api.%s/", domain)
+	}
+
+	parsedAPIURL, parseErr := url.Parse(apiURL)
+	if parseErr != nil {
+		return nil, fmt.Errorf(`invalid API URL : %v`, parseErr)
+	}
+	if parsedAPIURL.Host == "" || parsedAPIURL.Scheme == "" {
+		return nil, fmt.Errorf(`missing protocol or host : %v`, apiURL)
+	}
 
-	return ctx, apiClient, configuration, nil
+	return parsedAPIURL, nil
 }
 
 //// TRANSFORM FUNCTIONS